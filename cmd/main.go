@@ -2,17 +2,24 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/queue"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/routes"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func init() {
+	// The process executor starts this binary again to set up the sandbox before running user code
+	if len(os.Args) > 1 && os.Args[1] == constants.SANDBOX_INIT_COMMAND {
+		services.RunSandboxInit(os.Args[2:])
+	}
+
 	// Read the config file
 	err := config.NewEnv()
 	if err != nil {
@@ -45,6 +52,7 @@ func main() {
 	routes.BlogRoutes(r)
 	routes.CodeExecutionRoutes(r)
 	routes.ChallengeRoutes(r)
+	routes.SubmissionRoutes(r)
 
 	// rabbitmq setup
 	err := queue.InitializeRabbitMQ()
@@ -61,6 +69,24 @@ func main() {
 	// Connect to redis
 	services.InitializeRedis()
 
+	// set up the code executor
+	err = services.InitializeExecutor()
+	if err != nil {
+		log.Fatalf("Failed to initialize the code executor: %v", err)
+	}
+
+	// start the judge workers once the executor is ready
+	err = queue.StartJudgeConsumer()
+	if err != nil {
+		log.Fatalf("Failed to start the judge consumer: %v", err)
+	}
+
+	// compare accepted submissions for plagiarism in the background
+	err = services.StartPlagiarismDetector()
+	if err != nil {
+		log.Fatalf("Failed to start the plagiarism detector: %v", err)
+	}
+
 	// start the server
 	err = r.Run(":" + config.Config.PORT)
 	if err != nil {
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/queue"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	request "github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/request/auth"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/response"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/utils"
@@ -16,8 +19,53 @@ import (
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// startSession creates a session for the user and sets its access and refresh tokens in cookies
+func startSession(c *gin.Context, payload utils.JWTPayload) error {
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := models.CreateSession(&models.Session{
+		UserID:           payload.ID,
+		Username:         payload.Username,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		ExpiresAt:        time.Now().Add(config.Config.REFRESH_TOKEN_TTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	payload.SessionID = sessionID
+	token, err := utils.GenerateToken(payload)
+	if err != nil {
+		return err
+	}
+
+	setAccessTokenCookie(c, token)
+	setRefreshTokenCookie(c, refreshToken)
+	return nil
+}
+
+func setAccessTokenCookie(c *gin.Context, token string) {
+	c.SetCookie(config.Config.JWT_TOKEN_COOKIE, token, int(config.Config.ACCESS_TOKEN_TTL.Seconds()), "/", "", false, true)
+}
+
+// the refresh token is only sent to the auth api
+func setRefreshTokenCookie(c *gin.Context, token string) {
+	c.SetCookie(config.Config.REFRESH_TOKEN_COOKIE, token, int(config.Config.REFRESH_TOKEN_TTL.Seconds()), constants.AUTH_API_BASE_ENDPOINT, "", false, true)
+}
+
+func clearSessionCookies(c *gin.Context) {
+	c.SetCookie(config.Config.JWT_TOKEN_COOKIE, "", -1, "/", "", false, true)
+	c.SetCookie(config.Config.REFRESH_TOKEN_COOKIE, "", -1, constants.AUTH_API_BASE_ENDPOINT, "", false, true)
+}
+
 func Register(c *gin.Context) {
 	var body request.RegisterRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -66,6 +114,7 @@ func Register(c *gin.Context) {
 			ChallengesTaken: []string{},
 			Stats: models.Stats{
 				QuestionsSubmitted: 0,
+				QuestionsAccepted:  0,
 				QuestionsCreated:   0,
 				BlogsCreated:       0,
 				ChallengesCreated:  0,
@@ -94,21 +143,19 @@ func Register(c *gin.Context) {
 			return
 		}
 
-		// set jwt token in cookie
-		token, err := utils.GenerateToken(utils.JWTPayload{
+		// set the session tokens in cookies
+		err = startSession(c, utils.JWTPayload{
 			ID:       ID,
 			Name:     body.Name,
 			Email:    body.Email,
 			Username: body.Username,
 		})
 		if err != nil {
-			logrus.Errorf("Error generating the token: Login API: %v", err)
+			logrus.Errorf("Error starting the session: Register API: %v", err)
 			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
 			return
 		}
 
-		c.SetCookie(config.Config.JWT_TOKEN_COOKIE, token, 24*60*60, "/", "localhost", false, true)
-
 		responseData := struct {
 			ID       string `json:"id"`
 			Name     string `json:"name"`
@@ -123,6 +170,7 @@ func Register(c *gin.Context) {
 			Username: body.Username,
 			Stats: models.Stats{
 				QuestionsSubmitted: 0,
+				QuestionsAccepted:  0,
 				QuestionsCreated:   0,
 				BlogsCreated:       0,
 				ChallengesCreated:  0,
@@ -196,21 +244,19 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// set jwt token in cookie
-	token, err := utils.GenerateToken(utils.JWTPayload{
+	// set the session tokens in cookies
+	err = startSession(c, utils.JWTPayload{
 		ID:       decodedUser.ID,
 		Name:     decodedUser.Name,
 		Email:    decodedUser.Email,
 		Username: decodedUser.Username,
 	})
 	if err != nil {
-		logrus.Errorf("Error generating the token: Login API: %v", err)
+		logrus.Errorf("Error starting the session: Login API: %v", err)
 		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
 		return
 	}
 
-	c.SetCookie(config.Config.JWT_TOKEN_COOKIE, token, 24*60*60, "/", "", false, true)
-
 	responseData := struct {
 		ID       string `json:"id"`
 		Name     string `json:"name"`
@@ -229,11 +275,206 @@ func Login(c *gin.Context) {
 	response.HandleResponse(c, http.StatusOK, "Login successful", responseData)
 }
 
+// RefreshToken exchanges the refresh token of a session for a new access token and a new refresh token.
+// A refresh token can only be used once, using an old one again means it was stolen and ends the session.
+func RefreshToken(c *gin.Context) {
+	refreshToken, err := c.Cookie(config.Config.REFRESH_TOKEN_COOKIE)
+	if err != nil || refreshToken == "" {
+		logrus.Errorf("Refresh token not found: RefreshToken API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized - Refresh token not found", nil)
+		return
+	}
+
+	newRefreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		logrus.Errorf("Error generating the refresh token: RefreshToken API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	tokenHash := utils.HashToken(refreshToken)
+	rotated := true
+	session, err := models.RotateSession(tokenHash, utils.HashToken(newRefreshToken), time.Now().Add(config.Config.REFRESH_TOKEN_TTL), c.ClientIP())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		session, err = models.GetSessionByPreviousToken(tokenHash)
+		if err == nil && session.InRotationGracePeriod(tokenHash, time.Now()) {
+			// a concurrent refresh of the same client rotated the token, its response carries the new refresh token
+			// and this one only needs an access token. Rotating again would make one of the two tokens a reused one.
+			rotated = false
+		} else {
+			if err == nil && !session.Revoked {
+				logrus.Warnf("Refresh token reused, revoking session %s of user %s: RefreshToken API", session.ID, session.UserID)
+				if err := services.RevokeSession(session.ID); err != nil {
+					logrus.Errorf("Error revoking the session: RefreshToken API: %v", err)
+				}
+			}
+
+			clearSessionCookies(c)
+			response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized: Invalid refresh token", nil)
+			return
+		}
+	} else if err != nil {
+		logrus.Errorf("Error rotating the session: RefreshToken API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	// the old refresh token is spent, the new one has to reach the client whatever happens next
+	if rotated {
+		setRefreshTokenCookie(c, newRefreshToken)
+	}
+
+	user, err := models.GetUserById(session.UserID)
+	if err != nil {
+		logrus.Errorf("User not found: RefreshToken API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	username := user.Username
+	if username == "" {
+		username = session.Username
+	}
+
+	token, err := utils.GenerateToken(utils.JWTPayload{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Username:  username,
+		SessionID: session.ID,
+	})
+	if err != nil {
+		logrus.Errorf("Error generating the token: RefreshToken API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	setAccessTokenCookie(c, token)
+	response.HandleResponse(c, http.StatusOK, "Token refreshed successfully", nil)
+}
+
 func Logout(c *gin.Context) {
-	c.SetCookie(config.Config.JWT_TOKEN_COOKIE, "", -1, "/", "", false, true)
+	decodedUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: Logout API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	err = services.RevokeSession(decodedUser.SessionID)
+	if err != nil {
+		logrus.Errorf("Error revoking the session: Logout API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	clearSessionCookies(c)
 	response.HandleResponse(c, http.StatusOK, "Logout successful", nil)
 }
 
+// LogoutAll ends every session of the user, on all devices
+func LogoutAll(c *gin.Context) {
+	decodedUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: LogoutAll API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	err = services.RevokeUserSessions(decodedUser.ID, "")
+	if err != nil {
+		logrus.Errorf("Error revoking the sessions: LogoutAll API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	clearSessionCookies(c)
+	response.HandleResponse(c, http.StatusOK, "Logged out from all devices", nil)
+}
+
+// GetSessions lists the devices the user is logged in on
+func GetSessions(c *gin.Context) {
+	decodedUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GetSessions API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	sessions, err := models.GetActiveSessions(decodedUser.ID)
+	if err != nil {
+		logrus.Errorf("Error getting the sessions: GetSessions API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	type sessionResponse struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+
+	result := []sessionResponse{}
+	for _, session := range sessions {
+		result = append(result, sessionResponse{
+			Session: session,
+			Current: session.ID == decodedUser.SessionID,
+		})
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Sessions retrieved successfully", result)
+}
+
+// RevokeSession logs the user out of one of their devices
+func RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+
+	decodedUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: RevokeSession API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	session, err := models.GetSessionById(id)
+	if err != nil || session.UserID != decodedUser.ID {
+		logrus.Errorf("Session not found: RevokeSession API: %v", err)
+		response.HandleResponse(c, http.StatusNotFound, "Session not found", nil)
+		return
+	}
+
+	err = services.RevokeSession(session.ID)
+	if err != nil {
+		logrus.Errorf("Error revoking the session: RevokeSession API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	if session.ID == decodedUser.SessionID {
+		clearSessionCookies(c)
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Session revoked successfully", nil)
+}
+
+// RevokeOtherSessions logs the user out of every device but the current one
+func RevokeOtherSessions(c *gin.Context) {
+	decodedUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: RevokeOtherSessions API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	err = services.RevokeUserSessions(decodedUser.ID, decodedUser.SessionID)
+	if err != nil {
+		logrus.Errorf("Error revoking the sessions: RevokeOtherSessions API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Other sessions revoked successfully", nil)
+}
+
 func VerifyEmail(c *gin.Context) {
 	var body request.VerifyEmailRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -307,6 +548,8 @@ func VerifyEmail(c *gin.Context) {
 	response.HandleResponse(c, http.StatusOK, "Email Verified sucessfully", nil)
 }
 
+// ForgotPassword emails a single use password reset link to the user. It always succeeds,
+// so that it can't be used to find out which emails have an account.
 func ForgotPassword(c *gin.Context) {
 	var body request.ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -323,32 +566,89 @@ func ForgotPassword(c *gin.Context) {
 		return
 	}
 
+	message := "If an account exists with this email, a password reset link has been sent to it"
+
+	var user models.User
+	err = database.UserCollection.FindOne(context.TODO(), bson.M{"email": body.Email}).Decode(&user)
+	if err != nil {
+		logrus.Errorf("User not found: ForgotPassword API: %v", err)
+		response.HandleResponse(c, http.StatusOK, message, nil)
+		return
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		logrus.Errorf("Error generating the reset token: ForgotPassword API: %v", err)
+		response.HandleResponse(c, http.StatusOK, message, nil)
+		return
+	}
+
+	err = models.SetPasswordResetToken(user.ID, utils.HashToken(token), time.Now().Add(config.Config.PASSWORD_RESET_TOKEN_TTL))
+	if err != nil {
+		logrus.Errorf("Error saving the reset token: ForgotPassword API: %v", err)
+		response.HandleResponse(c, http.StatusOK, message, nil)
+		return
+	}
+
+	// send the reset link through rabbitmq
+	err = queue.StartProducer(queue.EmailVerificationPayload{
+		Type:     constants.EMAIL_TYPE_PASSWORD_RESET,
+		Email:    user.Email,
+		Username: user.Name,
+		Token:    token,
+	})
+	if err != nil {
+		logrus.Errorf("Error queueing the reset email: ForgotPassword API: %v", err)
+	}
+
+	response.HandleResponse(c, http.StatusOK, message, nil)
+}
+
+// ResetPassword sets a new password with the token of a reset link and logs the user out everywhere
+func ResetPassword(c *gin.Context) {
+	var body request.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		logrus.Errorf("Invalid request body: ResetPassword API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	// validate the request body
+	err := utils.ValidateRequest(body)
+	if err != nil {
+		logrus.Errorf("Error validating the request body: ResetPassword API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Error validating the request body", nil)
+		return
+	}
+
 	if body.Password != body.ConfirmPassword {
-		logrus.Error("Passwords do not match: ForgotPassword API")
+		logrus.Error("Passwords do not match: ResetPassword API")
 		response.HandleResponse(c, http.StatusBadRequest, "Passwords do not match", nil)
 		return
 	}
 
-	result := database.UserCollection.FindOneAndUpdate(context.TODO(),
-		bson.M{
-			"$and": bson.A{
-				bson.M{"email": body.Email},
-				bson.M{"username": body.Username},
-			},
-		},
-		bson.M{
-			"$set": bson.M{
-				"password": utils.HashPassword(body.Password),
-			},
-		},
-	)
-	if result.Err() != nil {
-		logrus.Errorf("Error updating the user: ForgotPassword API: %v", result.Err())
-		response.HandleResponse(c, http.StatusInternalServerError, "User not found with the given email and username", nil)
+	user, err := models.ResetPassword(utils.HashToken(body.Token), utils.HashPassword(body.Password))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		logrus.Error("Invalid or expired reset token: ResetPassword API")
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid or expired reset token", nil)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Error updating the user: ResetPassword API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	// whoever knew the old password is logged out
+	err = services.RevokeUserSessions(user.ID, "")
+	if err != nil {
+		logrus.Errorf("Error revoking the sessions: ResetPassword API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
 		return
 	}
 
-	response.HandleResponse(c, http.StatusOK, "Password reset successful", nil)
+	clearSessionCookies(c)
+	response.HandleResponse(c, http.StatusOK, "Password reset successful. Please login", nil)
 }
 
 func ResendVerificationCodeViaEmail(c *gin.Context) {
@@ -2,16 +2,13 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
 	"net/http"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/queue"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
@@ -71,96 +68,101 @@ func ExecuteQuestion(c *gin.Context) {
 		return
 	}
 
-	// execute the question
-	if body.Type == constants.RUN_QUESTION || body.Type == constants.SUBMIT_QUESTION {
-		message := "Question Run Successful!"
-
-		var codeSnippet string
-		for _, snippet := range question.CodeSnippets {
-			if strings.ToLower(string(snippet.Language)) == body.Language {
-				codeSnippet = strings.TrimSpace(snippet.Code)
-				break
-			}
-		}
-
-		// generate the code by replacing placeholders
-		var code string
-		if body.Type == constants.RUN_QUESTION {
-			code = utils.GenerateCodeTemplate(question.TestCases[:2], body.Language, codeSnippet, body.Code)
-		} else if body.Type == constants.SUBMIT_QUESTION {
-			code = utils.GenerateCodeTemplate(question.TestCases, body.Language, codeSnippet, body.Code)
+	// run the sample test cases right away
+	if body.Type == constants.RUN_QUESTION {
+		ctx := services.WithExecutionOwner(c.Request.Context(), decodeUser.ID, false)
+		result, results, err := services.RunQuestion(ctx, &question, body.Language, body.Code, question.SampleTestCases())
+		if respondIfExecutionQueueFull(c, err) {
+			logrus.Errorf("Execution queue is full: ExecuteQuestion API: %v", err)
+			return
 		}
-
-		// run the code for given language in its container
-		res, err := services.ExecuteCodeInDocker(body.Language, code)
 		if err != nil {
 			logrus.Errorf("Error running the code: ExecuteQuestion API: %v", err)
 			response.HandleResponse(c, http.StatusInternalServerError, err.Error(), nil)
 			return
 		}
 
-		// create an entry into the database
-		if body.Type == constants.SUBMIT_QUESTION {
-			message = "Question Submission Successful!"
-
-			_, err := models.CreateSubmission(&models.QuestionSubmission{
-				QuestionID: questionId,
-				UserID: decodeUser.ID,
-				Language: body.Language,
-				Code: body.Code,
-				CreatedAt: time.Now(),
-			})
-			if err != nil {
-				logrus.Errorf("Error creating submission: ExecuteQuestion API: %v", err)
-				response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
-				return
-			}
-
-			// update user collection
-			userObjectId, err := primitive.ObjectIDFromHex(decodeUser.ID)
-			if err != nil {
-				logrus.Errorf("Could not convert user id into object id: RunQuestionHandler API: %v", nil)
-				response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
-				return
-			}
-			result := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.USER_COLLECTION).FindOneAndUpdate(
-				context.TODO(), 
-				bson.M{
-					"_id": userObjectId,
-				}, 
-				bson.M{
-					"$push": bson.M{
-						"questions_submitted": questionId,
-					},
-					"$inc": bson.M{
-						"stats.questions_submitted": 1,
-					},
-				},
-			)
-			if result.Err() != nil {
-				logrus.Errorf("Error updating user collection: RunQuestionHandler API: %v", result.Err())
-				response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
-				return
-			}
+		responseData := struct {
+			Execution *services.ExecutionResult `json:"execution"`
+			Results   []models.TestCaseResult   `json:"results"`
+		}{
+			Execution: result,
+			Results:   results,
+		}
+		response.HandleResponse(c, http.StatusOK, "Question Run Successful!", responseData)
+		return
+	} else if body.Type == constants.SUBMIT_QUESTION {
+		// create an entry into the database, the judge picks it up from the queue
+		submission, err := models.CreateSubmission(&models.QuestionSubmission{
+			QuestionID: questionId,
+			UserID: decodeUser.ID,
+			Language: body.Language,
+			Code: body.Code,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			logrus.Errorf("Error creating submission: ExecuteQuestion API: %v", err)
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			return
 		}
 
-		// convert res into proper format
-		cleanedData := strings.Trim(res, "\u0001\u0000\n")
-		reg := regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F\x7F]`)
-		cleanedData = reg.ReplaceAllString(cleanedData, "")
+		submissionObjectId, ok := submission.InsertedID.(primitive.ObjectID)
+		if !ok {
+			logrus.Errorf("InsertedID is not a valid ObjectID: ExecuteQuestion API: %v", submission.InsertedID)
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			return
+		}
+		submissionId := submissionObjectId.Hex()
 
-		fmt.Println(cleanedData)
+		// update user collection
+		userObjectId, err := primitive.ObjectIDFromHex(decodeUser.ID)
+		if err != nil {
+			logrus.Errorf("Could not convert user id into object id: ExecuteQuestion API: %v", nil)
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			return
+		}
+		result := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.USER_COLLECTION).FindOneAndUpdate(
+			context.TODO(), 
+			bson.M{
+				"_id": userObjectId,
+			}, 
+			bson.M{
+				"$push": bson.M{
+					"questions_submitted": questionId,
+				},
+				"$inc": bson.M{
+					"stats.questions_submitted": 1,
+				},
+			},
+		)
+		if result.Err() != nil {
+			logrus.Errorf("Error updating user collection: ExecuteQuestion API: %v", result.Err())
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			return
+		}
 
-		var responses []services.Response
-		err = json.Unmarshal([]byte(cleanedData), &responses)
+		err = queue.PublishSubmission(queue.SubmissionPayload{
+			SubmissionID: submissionId,
+		})
 		if err != nil {
-			log.Fatalf("Failed to unmarshal cleaned data: %v", err)
+			logrus.Errorf("Error publishing submission to the judge queue: ExecuteQuestion API: %v", err)
+			// the judge never sees it, so it must not stay queued
+			if err := services.FailSubmission(submissionId, err); err != nil {
+				logrus.Errorf("Error failing submission: ExecuteQuestion API: %v", err)
+			}
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			return
 		}
 
-		response.HandleResponse(c, http.StatusOK, message, responses)
+		responseData := struct {
+			SubmissionID string `json:"submission_id"`
+		}{
+			SubmissionID: submissionId,
+		}
+		response.HandleResponse(c, http.StatusAccepted, "Question Submission Queued!", responseData)
 		return
 	} else {
-		logrus.Errorf("Invalid execution operation: RunQuestionHandler API: %v", nil)
+		logrus.Errorf("Invalid execution operation: ExecuteQuestion API: %v", nil)
 		response.HandleResponse(c, http.StatusBadRequest, "Invalid execution operation", nil)
 		return
 	}
@@ -180,17 +182,81 @@ func ExecuteCompilerCode(c *gin.Context) {
 		return
 	}
 
+	// a project is given as a list of files, every path may only appear once
+	var files map[string]string
+	if len(body.Files) > 0 {
+		files = map[string]string{}
+		for _, file := range body.Files {
+			if _, ok := files[file.Path]; ok {
+				logrus.Errorf("Duplicate file path: ExecuteCompilerCode API: %s", file.Path)
+				response.HandleResponse(c, http.StatusBadRequest, "Duplicate file path: "+file.Path, nil)
+				return
+			}
+			files[file.Path] = file.Content
+		}
+
+		if err := services.ValidateProject(body.Language, files, body.Entry); err != nil {
+			logrus.Errorf("Invalid project: ExecuteCompilerCode API: %v", err)
+			response.HandleResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: ExecuteCompilerCode API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
 	// run the code
-	output, err := services.ExecuteCodeInDocker(body.Language, body.Code)
+	ctx := services.WithExecutionOwner(c.Request.Context(), decodeUser.ID, false)
+	result, err := services.CodeExecutor.Execute(ctx, services.ExecutionRequest{
+		Language: body.Language,
+		Code:     body.Code,
+		Files:    files,
+		Entry:    body.Entry,
+		Stdin:    body.Stdin,
+		Args:     body.Args,
+		Limits:   services.DefaultLimits,
+		Timeout:  services.DefaultTimeout,
+	})
+	if respondIfExecutionQueueFull(c, err) {
+		logrus.Errorf("Execution queue is full: ExecuteCompilerCode API: %v", err)
+		return
+	}
 	if err != nil {
 		logrus.Errorf("Error running the code: ExecuteCompilerCode API: %v", err)
 		response.HandleResponse(c, http.StatusInternalServerError, err.Error(), nil)
 		return
 	}
 
-	cleanedData := strings.Trim(output, "\u0001\u0000\n")
-	reg := regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F\x7F]`)
-	cleanedData = reg.ReplaceAllString(cleanedData, "")
+	// the input is sent back with the output so the page can show what the program ran with
+	responseData := struct {
+		*services.ExecutionResult
+		Stdin string   `json:"stdin"`
+		Args  []string `json:"args"`
+	}{
+		ExecutionResult: result,
+		Stdin:           body.Stdin,
+		Args:            body.Args,
+	}
+	response.HandleResponse(c, http.StatusOK, "Code executed successfully", responseData)
+}
+
+// respondIfExecutionQueueFull responds with 503 and the queue position when there is no room to run the code
+func respondIfExecutionQueueFull(c *gin.Context, err error) bool {
+	var queueFullErr *services.QueueFullError
+	if !errors.As(err, &queueFullErr) {
+		return false
+	}
 
-	response.HandleResponse(c, http.StatusOK, "Code executed successfully", cleanedData)
-}
\ No newline at end of file
+	c.Header("Retry-After", "5")
+	responseData := struct {
+		QueuePosition int `json:"queue_position"`
+	}{
+		QueuePosition: queueFullErr.Position,
+	}
+	response.HandleResponse(c, http.StatusServiceUnavailable, "Too many executions, please try again later", responseData)
+	return true
+}
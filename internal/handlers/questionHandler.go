@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	request "github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/request/auth"
@@ -36,6 +39,20 @@ func CreateQuestion(c *gin.Context) {
 		return
 	}
 
+	if body.Signature != nil {
+		if err := body.Signature.Validate(); err != nil {
+			logrus.Errorf("Invalid signature: CreateQuestion API: %v", err)
+			response.HandleResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+
+	if body.Mode == models.InteractiveMode && body.Interactor == nil {
+		logrus.Error("Interactive question without interactor: CreateQuestion API")
+		response.HandleResponse(c, http.StatusBadRequest, "An interactive question needs an interactor", nil)
+		return
+	}
+
 	// get the data from context
 	decodeUser, err := utils.GetDecodedUserFromContext(c)
 	if err != nil {
@@ -44,17 +61,47 @@ func CreateQuestion(c *gin.Context) {
 		return
 	}
 
-	result, err := models.CreateQuestion(&models.Question{
-		Title:        body.Title,
-		Description:  body.Description,
-		Difficulty:   body.Difficulty,
-		Tags:         body.Tags,
-		Companies:    body.Companies,
-		Hints:        body.Hints,
-		TestCases:    body.TestCases,
-		CodeSnippets: body.CodeSnippets,
-		AuthorID:     decodeUser.ID,
-	})
+	question := &models.Question{
+		Title:             body.Title,
+		Description:       body.Description,
+		Difficulty:        body.Difficulty,
+		Tags:              body.Tags,
+		Companies:         body.Companies,
+		Hints:             body.Hints,
+		Mode:              body.Mode,
+		TimeLimit:         body.TimeLimit,
+		MemoryLimit:       body.MemoryLimit,
+		TestCases:         body.TestCases,
+		Signature:         body.Signature,
+		Checker:           body.Checker,
+		Interactor:        body.Interactor,
+		CodeSnippets:      body.CodeSnippets,
+		ReferenceSolution: body.ReferenceSolution,
+		Generator:         body.Generator,
+		AuthorID:          decodeUser.ID,
+	}
+
+	// the test cases are checked against the reference solution, a question failing it is created as rejected
+	if question.ReferenceSolution != nil {
+		ctx := services.WithExecutionOwner(c.Request.Context(), decodeUser.ID, false)
+		report, err := services.ValidateQuestion(ctx, question)
+		if respondIfExecutionQueueFull(c, err) {
+			logrus.Errorf("Execution queue is full: CreateQuestion API: %v", err)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("Error validating the question: CreateQuestion API: %v", err)
+			response.HandleResponse(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+
+		question.Validation = report
+		if !report.Valid {
+			question.Status = models.Rejected
+		}
+	}
+
+	result, err := models.CreateQuestion(question)
 	if err != nil {
 		logrus.Errorf("Error creating question: CreateQuestion API: %v", err)
 		response.HandleResponse(c, http.StatusInternalServerError, "Failed to create question", nil)
@@ -86,7 +133,20 @@ func CreateQuestion(c *gin.Context) {
 		return
 	}
 
-	response.HandleResponse(c, http.StatusCreated, "Question created successfully", result)
+	responseData := struct {
+		InsertedID interface{}              `json:"InsertedID"`
+		Validation *models.ValidationReport `json:"validation,omitempty"`
+	}{
+		InsertedID: result.InsertedID,
+		Validation: question.Validation,
+	}
+
+	if question.Status == models.Rejected {
+		response.HandleResponse(c, http.StatusCreated, "Question created but rejected, the reference solution failed its test cases", responseData)
+		return
+	}
+
+	response.HandleResponse(c, http.StatusCreated, "Question created successfully", responseData)
 }
 
 func GetAllQuestions(c *gin.Context) {
@@ -140,6 +200,19 @@ func GetAllQuestions(c *gin.Context) {
 		return
 	}
 
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GetAllQuestions API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	for i := range result {
+		if result[i].AuthorID != decodeUser.ID {
+			result[i].RemoveHiddenData()
+		}
+	}
+
 	response.HandleResponse(c, http.StatusOK, "Questions retrieved successfully", result)
 }
 
@@ -167,6 +240,17 @@ func GetQuestionById(c *gin.Context) {
 		return
 	}
 
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GetQuestionById API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	if question.AuthorID != decodeUser.ID {
+		question.RemoveHiddenData()
+	}
+
 	response.HandleResponse(c, http.StatusOK, "Question retrieved successfully", question)
 }
 
@@ -194,6 +278,25 @@ func UpdateQuestion(c *gin.Context) {
 		return
 	}
 
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: UpdateQuestion API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	user, err := models.GetUserById(decodeUser.ID)
+	if err != nil {
+		logrus.Errorf("User not found: UpdateQuestion API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	if questionToUpdate.AuthorID != decodeUser.ID && !user.IsAdmin() {
+		response.HandleResponse(c, http.StatusForbidden, "Only the author and admins can update the question", nil)
+		return
+	}
+
 	var question request.UpdateQuestionRequest
 	if err := c.ShouldBindJSON(&question); err != nil {
 		logrus.Errorf("Invalid request body: UpdateQuestion API: %v", err)
@@ -201,6 +304,12 @@ func UpdateQuestion(c *gin.Context) {
 		return
 	}
 
+	if err := utils.ValidateRequest(question); err != nil {
+		logrus.Errorf("Error validating the request body: UpdateQuestion API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
 	if question.Title != "" && question.Title != questionToUpdate.Title {
 		questionToUpdate.Title = question.Title
 
@@ -230,25 +339,103 @@ func UpdateQuestion(c *gin.Context) {
 		questionToUpdate.Hints = question.Hints
 	}
 
+	if question.Mode != "" && question.Mode != questionToUpdate.Mode {
+		questionToUpdate.Mode = question.Mode
+	}
+
+	if question.TimeLimit != 0 && question.TimeLimit != questionToUpdate.TimeLimit {
+		questionToUpdate.TimeLimit = question.TimeLimit
+	}
+
+	if question.MemoryLimit != 0 && question.MemoryLimit != questionToUpdate.MemoryLimit {
+		questionToUpdate.MemoryLimit = question.MemoryLimit
+	}
+
 	if question.TestCases != nil && !reflect.DeepEqual(question.TestCases, questionToUpdate.TestCases) {
 		questionToUpdate.TestCases = question.TestCases
 	}
 
+	if question.Signature != nil {
+		if err := question.Signature.Validate(); err != nil {
+			logrus.Errorf("Invalid signature: UpdateQuestion API: %v", err)
+			response.HandleResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		questionToUpdate.Signature = question.Signature
+	}
+
+	if question.Checker != nil {
+		questionToUpdate.Checker = question.Checker
+	}
+
+	if question.Interactor != nil {
+		questionToUpdate.Interactor = question.Interactor
+	}
+
+	if questionToUpdate.Mode == models.InteractiveMode && questionToUpdate.Interactor == nil {
+		logrus.Error("Interactive question without interactor: UpdateQuestion API")
+		response.HandleResponse(c, http.StatusBadRequest, "An interactive question needs an interactor", nil)
+		return
+	}
+
 	if question.CodeSnippets != nil && !reflect.DeepEqual(question.CodeSnippets, questionToUpdate.CodeSnippets) {
 		questionToUpdate.CodeSnippets = question.CodeSnippets
 	}
 
+	if question.ReferenceSolution != nil {
+		questionToUpdate.ReferenceSolution = question.ReferenceSolution
+	}
+
+	if question.Generator != nil {
+		questionToUpdate.Generator = question.Generator
+	}
+
+	// anything the judge uses may have changed, so the test cases are checked against the reference solution again
+	judgeChanged := question.Mode != "" || question.TimeLimit != 0 || question.MemoryLimit != 0 || question.TestCases != nil ||
+		question.Signature != nil || question.Checker != nil || question.Interactor != nil || question.CodeSnippets != nil || question.ReferenceSolution != nil
+	if questionToUpdate.ReferenceSolution != nil && judgeChanged {
+		ctx := services.WithExecutionOwner(c.Request.Context(), decodeUser.ID, false)
+		report, err := services.ValidateQuestion(ctx, &questionToUpdate)
+		if respondIfExecutionQueueFull(c, err) {
+			logrus.Errorf("Execution queue is full: UpdateQuestion API: %v", err)
+			return
+		}
+		if err != nil {
+			logrus.Errorf("Error validating the question: UpdateQuestion API: %v", err)
+			response.HandleResponse(c, http.StatusInternalServerError, err.Error(), nil)
+			return
+		}
+
+		// only a rejection because of the validation is lifted when the question passes again
+		if !report.Valid {
+			questionToUpdate.Status = models.Rejected
+		} else if questionToUpdate.Status == models.Rejected && questionToUpdate.Validation != nil && !questionToUpdate.Validation.Valid {
+			questionToUpdate.Status = models.Pending
+		}
+		questionToUpdate.Validation = report
+	}
+
 	updateStage := bson.M{
 		"$set": bson.M{
-			"title":        questionToUpdate.Title,
-			"description":  questionToUpdate.Description,
-			"difficulty":   questionToUpdate.Difficulty,
-			"tags":         questionToUpdate.Tags,
-			"companies":    questionToUpdate.Companies,
-			"hints":        questionToUpdate.Hints,
-			"testCases":    questionToUpdate.TestCases,
-			"codeSnippets": questionToUpdate.CodeSnippets,
-			"slug":         questionToUpdate.Slug,
+			"title":             questionToUpdate.Title,
+			"description":       questionToUpdate.Description,
+			"difficulty":        questionToUpdate.Difficulty,
+			"tags":              questionToUpdate.Tags,
+			"companies":         questionToUpdate.Companies,
+			"hints":             questionToUpdate.Hints,
+			"mode":              questionToUpdate.Mode,
+			"timeLimit":         questionToUpdate.TimeLimit,
+			"memoryLimit":       questionToUpdate.MemoryLimit,
+			"testCases":         questionToUpdate.TestCases,
+			"signature":         questionToUpdate.Signature,
+			"checker":           questionToUpdate.Checker,
+			"interactor":        questionToUpdate.Interactor,
+			"codeSnippets":      questionToUpdate.CodeSnippets,
+			"referenceSolution": questionToUpdate.ReferenceSolution,
+			"generator":         questionToUpdate.Generator,
+			"validation":        questionToUpdate.Validation,
+			"status":            questionToUpdate.Status,
+			"slug":              questionToUpdate.Slug,
 		},
 	}
 	res, updateErr := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.QUESTION_COLLECTION).UpdateOne(context.TODO(), bson.M{"_id": objectId}, updateStage)
@@ -263,6 +450,10 @@ func UpdateQuestion(c *gin.Context) {
 		return
 	}
 
+	if questionToUpdate.AuthorID != decodeUser.ID {
+		questionToUpdate.RemoveHiddenData()
+	}
+
 	response.HandleResponse(c, http.StatusOK, "Question updated successfully", questionToUpdate)
 }
 
@@ -310,6 +501,14 @@ func DeleteQuestion(c *gin.Context) {
 		return
 	}
 
+	// the question no longer counts as accepted, however many of the submissions were accepted
+	err = models.RemoveAcceptedQuestion(decodeUser.ID, id)
+	if err != nil {
+		logrus.Errorf("Error updating user stats [accept stats]: DeleteQuestion API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
 	if delResults.DeletedCount > 0 {
 		// update the user collection stats
 		_, err = database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.USER_COLLECTION).UpdateOne(
@@ -464,13 +663,20 @@ func GetSubmissionsOnAQuestion(c *gin.Context) {
 		return
 	}
 
+	filter := bson.M{
+		"question_id": id,
+		"user_id": decodeUser.ID,
+	}
+
+	// optionally only list the submissions with a verdict, e.g. ?verdict=Accepted
+	if verdict := c.Query("verdict"); verdict != "" {
+		filter["verdict"] = verdict
+	}
+
 	options := options.Find().SetSort(bson.M{"createdAt": -1})
 	cursor, err := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.CODE_SUBMISSION_COLLECTION).Find(
 		context.TODO(), 
-		bson.M{
-			"question_id": id,
-			"user_id": decodeUser.ID,
-		}, 
+		filter, 
 		options,
 	)
 	if err != nil {
@@ -487,4 +693,193 @@ func GetSubmissionsOnAQuestion(c *gin.Context) {
 	}
 
 	response.HandleResponse(c, http.StatusOK, "Submissions retrieved successfully", submissions)
-}
\ No newline at end of file
+}
+
+// GenerateTestCases runs the generator of a question and appends the generated test cases as hidden ones,
+// their expected outputs are computed by the reference solution
+func GenerateTestCases(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		logrus.Errorf("Invalid question id: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid question id", nil)
+		return
+	}
+
+	var body request.GenerateTestCasesRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		logrus.Errorf("Invalid request body: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if err := utils.ValidateRequest(body); err != nil {
+		logrus.Errorf("Error validating the request body: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	question, err := models.GetQuestionById(id)
+	if err != nil {
+		logrus.Errorf("Question not found: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusNotFound, "Question not found", nil)
+		return
+	}
+
+	if question.AuthorID != decodeUser.ID {
+		response.HandleResponse(c, http.StatusForbidden, "Only the author can generate test cases", nil)
+		return
+	}
+
+	if question.Generator == nil || question.ReferenceSolution == nil {
+		response.HandleResponse(c, http.StatusBadRequest, "The question needs a generator and a reference solution", nil)
+		return
+	}
+
+	// the seeds are recorded with the test cases, so a random first seed is reproducible as well
+	seed := body.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	ctx := services.WithExecutionOwner(c.Request.Context(), decodeUser.ID, false)
+	testCases, err := services.GenerateTestCases(ctx, question, body.Count, seed)
+	if respondIfExecutionQueueFull(c, err) {
+		logrus.Errorf("Execution queue is full: GenerateTestCases API: %v", err)
+		return
+	}
+
+	var generationErr *services.GenerationError
+	if errors.As(err, &generationErr) {
+		logrus.Errorf("Error generating the test cases: GenerateTestCases API: %v", err)
+		responseData := struct {
+			Seed    *int64         `json:"seed,omitempty"`
+			Verdict models.Verdict `json:"verdict"`
+			Stderr  string         `json:"stderr"`
+		}{
+			Seed:    generationErr.Seed,
+			Verdict: generationErr.Verdict,
+			Stderr:  generationErr.Stderr,
+		}
+		response.HandleResponse(c, http.StatusUnprocessableEntity, generationErr.Error(), responseData)
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Error generating the test cases: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	if len(testCases) == 0 {
+		response.HandleResponse(c, http.StatusOK, "The generator produced no new test cases", testCases)
+		return
+	}
+
+	question.AddTestCases(testCases)
+
+	// the generated test cases are passed by the reference solution by construction
+	updateStage := bson.M{
+		"testCases": question.TestCases,
+	}
+	if question.Validation != nil {
+		updateStage["validation.passed"] = question.Validation.Passed + len(testCases)
+		updateStage["validation.total"] = question.Validation.Total + len(testCases)
+	}
+
+	_, err = database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.QUESTION_COLLECTION).UpdateOne(context.TODO(), bson.M{"_id": objectId}, bson.M{"$set": updateStage})
+	if err != nil {
+		logrus.Errorf("Error updating question: GenerateTestCases API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Test cases generated successfully", testCases)
+}
+
+// GetSimilarSubmissions lists the pairs of accepted submissions of a question which are suspiciously similar,
+// with the matching lines of both. Only the author of the question and admins can see them.
+func GetSimilarSubmissions(c *gin.Context) {
+	id := c.Param("id")
+
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GetSimilarSubmissions API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	question, err := models.GetQuestionById(id)
+	if err != nil {
+		logrus.Errorf("Question not found: GetSimilarSubmissions API: %v", err)
+		response.HandleResponse(c, http.StatusNotFound, "Question not found", nil)
+		return
+	}
+
+	user, err := models.GetUserById(decodeUser.ID)
+	if err != nil {
+		logrus.Errorf("User not found: GetSimilarSubmissions API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	if question.AuthorID != decodeUser.ID && !user.IsAdmin() {
+		response.HandleResponse(c, http.StatusForbidden, "Only the author and admins can see similar submissions", nil)
+		return
+	}
+
+	// optionally only list the most similar pairs, e.g. ?min_score=0.9
+	minScore := config.Config.PLAGIARISM_THRESHOLD
+	if value := c.Query("min_score"); value != "" {
+		minScore, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			logrus.Errorf("Invalid min score: GetSimilarSubmissions API: %v", err)
+			response.HandleResponse(c, http.StatusBadRequest, "Invalid min score", nil)
+			return
+		}
+	}
+
+	pairs, err := models.GetSimilarityPairs(id, minScore)
+	if err != nil {
+		logrus.Errorf("Error getting the similar submissions: GetSimilarSubmissions API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+		return
+	}
+
+	// the code of both submissions is sent along, so the matching lines can be highlighted
+	type similarSubmissions struct {
+		models.SimilarityPair
+		CodeA string `json:"code_a"`
+		CodeB string `json:"code_b"`
+	}
+
+	result := []similarSubmissions{}
+	for _, pair := range pairs {
+		submissionA, err := models.GetSubmissionById(pair.SubmissionA)
+		if err != nil {
+			logrus.Errorf("Error getting submission %s: GetSimilarSubmissions API: %v", pair.SubmissionA, err)
+			continue
+		}
+
+		submissionB, err := models.GetSubmissionById(pair.SubmissionB)
+		if err != nil {
+			logrus.Errorf("Error getting submission %s: GetSimilarSubmissions API: %v", pair.SubmissionB, err)
+			continue
+		}
+
+		result = append(result, similarSubmissions{
+			SimilarityPair: pair,
+			CodeA:          submissionA.Code,
+			CodeB:          submissionB.Code,
+		})
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Similar submissions retrieved successfully", result)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/response"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func GetSubmissionById(c *gin.Context) {
+	id := c.Param("id")
+
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: GetSubmissionById API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	submission, err := models.GetSubmissionById(id)
+	if err != nil {
+		logrus.Errorf("Submission not found: GetSubmissionById API: %v", err)
+		response.HandleResponse(c, http.StatusNotFound, "Submission not found", nil)
+		return
+	}
+
+	// users can only poll their own submissions
+	if submission.UserID != decodeUser.ID {
+		logrus.Error("Submission belongs to another user: GetSubmissionById API")
+		response.HandleResponse(c, http.StatusNotFound, "Submission not found", nil)
+		return
+	}
+
+	response.HandleResponse(c, http.StatusOK, "Submission retrieved successfully", submission)
+}
+
+// StreamSubmissionEvents streams the progress of a submission as server-sent events until its verdict
+func StreamSubmissionEvents(c *gin.Context) {
+	id := c.Param("id")
+
+	decodeUser, err := utils.GetDecodedUserFromContext(c)
+	if err != nil {
+		logrus.Errorf("Error getting decoded user: StreamSubmissionEvents API: %v", err)
+		response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	submission, err := models.GetSubmissionById(id)
+	if err != nil {
+		logrus.Errorf("Submission not found: StreamSubmissionEvents API: %v", err)
+		response.HandleResponse(c, http.StatusNotFound, "Submission not found", nil)
+		return
+	}
+
+	if submission.UserID != decodeUser.ID {
+		logrus.Error("Submission belongs to another user: StreamSubmissionEvents API")
+		response.HandleResponse(c, http.StatusNotFound, "Submission not found", nil)
+		return
+	}
+
+	subscription, err := services.SubscribeSubmissionEvents(c.Request.Context(), id)
+	if err != nil {
+		logrus.Errorf("Error subscribing to submission events: StreamSubmissionEvents API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Internal Server Error", nil)
+		return
+	}
+	defer subscription.Close()
+
+	// read the submission again now that we are subscribed, so a verdict published in between is not lost
+	submission, err = models.GetSubmissionById(id)
+	if err != nil {
+		logrus.Errorf("Error getting submission: StreamSubmissionEvents API: %v", err)
+		response.HandleResponse(c, http.StatusInternalServerError, "Internal Server Error", nil)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if submission.Status == models.Judged {
+		c.SSEvent(string(services.VerdictEvent), services.SubmissionEvent{
+			Type:         services.VerdictEvent,
+			SubmissionID: id,
+			Status:       submission.Status,
+			Verdict:      submission.Verdict,
+			Error:        submission.Error,
+		})
+		return
+	}
+
+	c.SSEvent(string(services.StatusEvent), services.SubmissionEvent{
+		Type:         services.StatusEvent,
+		SubmissionID: id,
+		Status:       submission.Status,
+	})
+
+	messages := subscription.Channel()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case message, ok := <-messages:
+			if !ok {
+				return false
+			}
+
+			var event services.SubmissionEvent
+			err := json.Unmarshal([]byte(message.Payload), &event)
+			if err != nil {
+				logrus.Errorf("Error unmarshalling submission event: StreamSubmissionEvents API: %v", err)
+				return true
+			}
+
+			c.SSEvent(string(event.Type), event)
+
+			// the stream ends with the verdict
+			return event.Type != services.VerdictEvent
+		}
+	})
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"net/http"
 
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/response"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/utils"
@@ -54,11 +55,36 @@ func Authorization() gin.HandlerFunc {
 			return
 		}
 
+		// tokens issued before sessions existed can't be revoked, so they are not accepted anymore
+		sessionID, ok := claims["sid"].(string)
+		if !ok || sessionID == "" {
+			logrus.Error("Unauthorized: Token without a session: Authorization Middleware")
+			response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized: Invalid token", nil)
+			c.Abort()
+			return
+		}
+
+		revoked, err := services.IsSessionRevoked(sessionID)
+		if err != nil {
+			logrus.Errorf("Error checking the session: Authorization Middleware: %v", err)
+			response.HandleResponse(c, http.StatusInternalServerError, "Something went wrong", nil)
+			c.Abort()
+			return
+		}
+
+		if revoked {
+			logrus.Errorf("Unauthorized: Session %s was revoked: Authorization Middleware", sessionID)
+			response.HandleResponse(c, http.StatusUnauthorized, "Unauthorized: Session expired", nil)
+			c.Abort()
+			return
+		}
+
 		userData := utils.JWTPayload{
-			ID:       claims["id"].(string),
-			Name:     claims["name"].(string),
-			Email:    claims["email"].(string),
-			Username: claims["username"].(string),
+			ID:        claims["id"].(string),
+			Name:      claims["name"].(string),
+			Email:     claims["email"].(string),
+			Username:  claims["username"].(string),
+			SessionID: sessionID,
 		}
 		c.Set(config.Config.JWT_DECODED_PAYLOAD, userData)
 
@@ -8,16 +8,71 @@ import (
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+type Verdict string
+
+type SubmissionStatus string
+
+const (
+	OK                  Verdict = "OK"
+	CompileError        Verdict = "Compile Error"
+	RuntimeError        Verdict = "Runtime Error"
+	TimeLimitExceeded   Verdict = "Time Limit Exceeded"
+	MemoryLimitExceeded Verdict = "Memory Limit Exceeded"
+	OutputLimitExceeded Verdict = "Output Limit Exceeded"
+	Accepted            Verdict = "Accepted"
+	WrongAnswer         Verdict = "Wrong Answer"
+
+	Queued  SubmissionStatus = "Queued"
+	Running SubmissionStatus = "Running"
+	Judged  SubmissionStatus = "Judged"
+)
+
+type TestCaseResult struct {
+	Input    string  `json:"input,omitempty" bson:"input,omitempty"`
+	Output   string  `json:"output,omitempty" bson:"output,omitempty"`
+	Expected string  `json:"expected,omitempty" bson:"expected,omitempty"`
+	Result   bool    `json:"result" bson:"result"`
+	Verdict  Verdict `json:"verdict,omitempty" bson:"verdict,omitempty"`
+	Time     int64   `json:"time,omitempty" bson:"time,omitempty"`     // cpu time in milliseconds
+	Memory   int64   `json:"memory,omitempty" bson:"memory,omitempty"` // peak memory in KiB
+	Hidden   bool    `json:"hidden,omitempty" bson:"hidden,omitempty"`
+}
+
+// Redacted hides the data of a hidden test case, only the verdict and usage are kept
+func (r TestCaseResult) Redacted() TestCaseResult {
+	return TestCaseResult{
+		Result:  r.Result,
+		Verdict: r.Verdict,
+		Time:    r.Time,
+		Memory:  r.Memory,
+		Hidden:  true,
+	}
+}
+
 type QuestionSubmission struct {
 	ID         string `json:"id" bson:"_id"`
 	QuestionID string `json:"question_id" bson:"question_id"`
 	UserID     string `json:"user_id" bson:"user_id"`
 	Language string `json:"language" bson:"language"`
 	Code     string `json:"code" bson:"code"`
+	Status   SubmissionStatus `json:"status" bson:"status"`
+	Verdict  Verdict          `json:"verdict,omitempty" bson:"verdict,omitempty"`
+	Results  []TestCaseResult `json:"results,omitempty" bson:"results,omitempty"`
+	Passed   int              `json:"passed" bson:"passed"`
+	Total    int              `json:"total" bson:"total"`
+	Runtime  int64            `json:"runtime,omitempty" bson:"runtime,omitempty"` // max cpu time of a test case in milliseconds
+	Memory   int64            `json:"memory,omitempty" bson:"memory,omitempty"`   // max peak memory of a test case in KiB
+	Stderr   string           `json:"stderr,omitempty" bson:"stderr,omitempty"` // compiler or runtime errors
+	Error    string           `json:"error,omitempty" bson:"error,omitempty"`   // the judge failed to run the submission
+	// winnowing fingerprints of accepted submissions, set once they were compared with the other submissions
+	Fingerprints      []int64 `json:"-" bson:"fingerprints,omitempty"`
+	SimilarityChecked bool    `json:"-" bson:"similarity_checked,omitempty"`
 	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	JudgedAt  time.Time `json:"judgedAt,omitempty" bson:"judgedAt,omitempty"`
 }
 
 func CreateSubmission(questionSubmission *QuestionSubmission) (*mongo.InsertOneResult, error) {
@@ -26,7 +81,39 @@ func CreateSubmission(questionSubmission *QuestionSubmission) (*mongo.InsertOneR
 		"user_id": questionSubmission.UserID,
 		"language": questionSubmission.Language,
 		"code": questionSubmission.Code,
+		"status": Queued,
 		"createdAt": questionSubmission.CreatedAt,
 	})
 	return result, err
-}
\ No newline at end of file
+}
+
+func GetSubmissionById(id string) (*QuestionSubmission, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.CODE_SUBMISSION_COLLECTION).FindOne(context.TODO(), bson.M{"_id": objectId})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var submission QuestionSubmission
+	if err := result.Decode(&submission); err != nil {
+		return nil, err
+	}
+
+	return &submission, nil
+}
+
+func UpdateSubmission(id string, fields bson.M) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.CODE_SUBMISSION_COLLECTION).UpdateOne(context.TODO(), bson.M{"_id": objectId}, bson.M{
+		"$set": fields,
+	})
+	return err
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -18,6 +21,12 @@ type QuestionStatus string
 
 type Language string
 
+type TestCaseVisibility string
+
+type CheckerType string
+
+type QuestionMode string
+
 const (
 	Easy   Difficulty = "Easy"
 	Medium Difficulty = "Medium"
@@ -29,12 +38,113 @@ const (
 
 	Python     Language = "Python"
 	JavaScript Language = "JavaScript"
+	Go         Language = "Go"
+	Cpp        Language = "Cpp"
+	Java       Language = "Java"
+
+	Sample TestCaseVisibility = "sample" // shown to everyone and used to run the code
+	Hidden TestCaseVisibility = "hidden" // only shown to the author, used to judge submissions
+
+	ExactChecker     CheckerType = "exact"     // output and expected output are equal
+	TokenChecker     CheckerType = "token"     // equal after splitting on whitespace
+	FloatChecker     CheckerType = "float"     // numbers may differ by the tolerance
+	UnorderedChecker CheckerType = "unordered" // the elements of a list (or the tokens) may be in any order
+	CustomChecker    CheckerType = "custom"    // a program of the author decides
+
+	FunctionMode    QuestionMode = "function"    // users implement a function called with the test case input
+	StdioMode       QuestionMode = "stdio"       // users write a program reading the test case input on stdin
+	InteractiveMode QuestionMode = "interactive" // users write a program talking to the interactor of the author over stdin and stdout
 )
 
 type TestCase struct {
-	Input       string `json:"input" bson:"input"`
-	Output      string `json:"output" bson:"output"`
-	Explanation string `json:"explanation,omitempty" bson:"explanation,omitempty"`
+	Input       string             `json:"input" bson:"input"`
+	Output      string             `json:"output" bson:"output"`
+	Explanation string             `json:"explanation,omitempty" bson:"explanation,omitempty"`
+	Visibility  TestCaseVisibility `json:"visibility,omitempty" bson:"visibility,omitempty" validate:"omitempty,oneof=sample hidden"`
+	Seed        *int64             `json:"seed,omitempty" bson:"seed,omitempty"` // seed the generator produced the input with
+}
+
+type Parameter struct {
+	Name string `json:"name" bson:"name" validate:"required"`
+	Type string `json:"type" bson:"type" validate:"required"`
+}
+
+// Signature is the function users implement for a question.
+// Types are int, long, double, bool, string, char, ListNode or TreeNode, followed by [] for arrays (e.g. int[][]).
+type Signature struct {
+	FunctionName string      `json:"functionName" bson:"functionName" validate:"required"`
+	Parameters   []Parameter `json:"parameters" bson:"parameters" validate:"dive"`
+	ReturnType   string      `json:"returnType" bson:"returnType" validate:"required"`
+}
+
+var (
+	identifierPattern    = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	signatureTypePattern = regexp.MustCompile(`^(int|long|double|bool|string|char|ListNode|TreeNode)(\[\])*$`)
+)
+
+func (s *Signature) Validate() error {
+	if !identifierPattern.MatchString(s.FunctionName) {
+		return fmt.Errorf("invalid function name: %s", s.FunctionName)
+	}
+
+	names := map[string]bool{}
+	for _, parameter := range s.Parameters {
+		if !identifierPattern.MatchString(parameter.Name) || names[parameter.Name] {
+			return fmt.Errorf("invalid parameter name: %s", parameter.Name)
+		}
+		names[parameter.Name] = true
+
+		if !signatureTypePattern.MatchString(parameter.Type) {
+			return fmt.Errorf("invalid type of parameter %s: %s", parameter.Name, parameter.Type)
+		}
+	}
+
+	if !signatureTypePattern.MatchString(s.ReturnType) {
+		return fmt.Errorf("invalid return type: %s", s.ReturnType)
+	}
+
+	return nil
+}
+
+// Checker decides whether an output is correct for a test case, questions without one use the exact checker.
+// A custom checker program reads {"input", "output", "expected"} as JSON on stdin and exits with 0 to accept.
+type Checker struct {
+	Type      CheckerType `json:"type" bson:"type" validate:"required,oneof=exact token float unordered custom"`
+	Tolerance float64     `json:"tolerance,omitempty" bson:"tolerance,omitempty" validate:"omitempty,gt=0"`
+	Language  string      `json:"language,omitempty" bson:"language,omitempty" validate:"required_if=Type custom"`
+	Code      string      `json:"code,omitempty" bson:"code,omitempty" validate:"required_if=Type custom"`
+}
+
+// Interactor is the program of the author an interactive question is judged by. It gets the test case input
+// as its only argument, talks to the user program over stdin and stdout and exits with 0 to accept,
+// anything it writes to stderr is shown as the output of the test case.
+type Interactor struct {
+	Language    string `json:"language" bson:"language" validate:"required,oneof=python javascript go cpp java"`
+	Code        string `json:"code" bson:"code" validate:"required"`
+	TurnTimeout int64  `json:"turnTimeout,omitempty" bson:"turnTimeout,omitempty" validate:"omitempty,min=100,max=10000"` // milliseconds the user program may take to answer
+}
+
+// ReferenceSolution is the solution of the author, every test case is checked against it
+type ReferenceSolution struct {
+	Language string `json:"language" bson:"language" validate:"required,oneof=python javascript go cpp java"`
+	Code     string `json:"code" bson:"code" validate:"required"`
+}
+
+// Generator is a program of the author which prints the input of a test case for the seed given as its argument
+type Generator struct {
+	Language string `json:"language" bson:"language" validate:"required,oneof=python javascript go cpp java"`
+	Code     string `json:"code" bson:"code" validate:"required"`
+}
+
+// ValidationReport is the outcome of running the reference solution against the test cases of a question
+type ValidationReport struct {
+	Valid       bool             `json:"valid" bson:"valid"`
+	Verdict     Verdict          `json:"verdict" bson:"verdict"`
+	Passed      int              `json:"passed" bson:"passed"`
+	Total       int              `json:"total" bson:"total"`
+	Failures    []TestCaseResult `json:"failures,omitempty" bson:"failures,omitempty"` // test cases the reference solution did not pass
+	Stderr      string           `json:"stderr,omitempty" bson:"stderr,omitempty"`
+	ValidatedAt time.Time        `json:"validatedAt" bson:"validatedAt"`
 }
 
 type CodeSnippet struct {
@@ -43,38 +153,123 @@ type CodeSnippet struct {
 }
 
 type Question struct {
-	ID           string         `json:"id" bson:"_id,omitempty"`
-	Title        string         `json:"title" bson:"title"`
-	Slug         string         `json:"slug" bson:"slug"`
-	Description  string         `json:"description" bson:"description"`
-	Difficulty   Difficulty     `json:"difficulty" bson:"difficulty"`
-	Tags         []string       `json:"tags" bson:"tags"`
-	Companies    []string       `json:"companies,omitempty" bson:"companies,omitempty"`
-	Hints        []string       `json:"hints,omitempty" bson:"hints,omitempty"`
-	TestCases    []TestCase     `json:"testCases" bson:"testCases"`
-	CodeSnippets []CodeSnippet  `json:"codeSnippets,omitempty" bson:"codeSnippets,omitempty"`
-	Status       QuestionStatus `json:"status" bson:"status"`
-	AuthorID     string         `json:"authorId,omitempty" bson:"authorId,omitempty"`
-	CreatedAt    time.Time      `json:"createdAt" bson:"createdAt"`
+	ID                string             `json:"id" bson:"_id,omitempty"`
+	Title             string             `json:"title" bson:"title"`
+	Slug              string             `json:"slug" bson:"slug"`
+	Description       string             `json:"description" bson:"description"`
+	Difficulty        Difficulty         `json:"difficulty" bson:"difficulty"`
+	Tags              []string           `json:"tags" bson:"tags"`
+	Companies         []string           `json:"companies,omitempty" bson:"companies,omitempty"`
+	Hints             []string           `json:"hints,omitempty" bson:"hints,omitempty"`
+	Mode              QuestionMode       `json:"mode,omitempty" bson:"mode,omitempty"`
+	TimeLimit         int64              `json:"timeLimit,omitempty" bson:"timeLimit,omitempty"`     // cpu time per test case in milliseconds
+	MemoryLimit       int64              `json:"memoryLimit,omitempty" bson:"memoryLimit,omitempty"` // memory per test case in MiB
+	TestCases         []TestCase         `json:"testCases" bson:"testCases"`
+	Signature         *Signature         `json:"signature,omitempty" bson:"signature,omitempty"`
+	Checker           *Checker           `json:"checker,omitempty" bson:"checker,omitempty"`
+	Interactor        *Interactor        `json:"interactor,omitempty" bson:"interactor,omitempty"` // only shown to the author
+	CodeSnippets      []CodeSnippet      `json:"codeSnippets,omitempty" bson:"codeSnippets,omitempty"`
+	ReferenceSolution *ReferenceSolution `json:"referenceSolution,omitempty" bson:"referenceSolution,omitempty"` // only shown to the author
+	Generator         *Generator         `json:"generator,omitempty" bson:"generator,omitempty"`                 // only shown to the author
+	Validation        *ValidationReport  `json:"validation,omitempty" bson:"validation,omitempty"`
+	Status            QuestionStatus     `json:"status" bson:"status"`
+	AuthorID          string             `json:"authorId,omitempty" bson:"authorId,omitempty"`
+	CreatedAt         time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// IsSampleTestCase reports whether the test case at index i can be shown to everyone.
+// Questions created before test cases had a visibility use their first test cases as samples.
+func (q *Question) IsSampleTestCase(i int) bool {
+	for _, testCase := range q.TestCases {
+		if testCase.Visibility != "" {
+			return q.TestCases[i].Visibility == Sample
+		}
+	}
+
+	return i < constants.SAMPLE_TEST_CASES
+}
+
+func (q *Question) SampleTestCases() []TestCase {
+	samples := []TestCase{}
+	for i, testCase := range q.TestCases {
+		if q.IsSampleTestCase(i) {
+			samples = append(samples, testCase)
+		}
+	}
+	return samples
+}
+
+// AddTestCases appends test cases to the question. Test cases of older questions get their visibility
+// first, otherwise adding a test case with a visibility would hide all of them.
+func (q *Question) AddTestCases(testCases []TestCase) {
+	samples := make([]bool, len(q.TestCases))
+	for i := range q.TestCases {
+		samples[i] = q.IsSampleTestCase(i)
+	}
+
+	for i := range q.TestCases {
+		if q.TestCases[i].Visibility != "" {
+			continue
+		}
+
+		q.TestCases[i].Visibility = Hidden
+		if samples[i] {
+			q.TestCases[i].Visibility = Sample
+		}
+	}
+
+	q.TestCases = append(q.TestCases, testCases...)
+}
+
+// RemoveHiddenData drops the hidden test cases, the code of a custom checker, the interactor, the reference solution
+// and the generator. Only the author of a question can see them, every other user gets the question without them.
+// Submitted code does not see them either, the judge only passes the test case being run into the sandbox.
+func (q *Question) RemoveHiddenData() {
+	q.TestCases = q.SampleTestCases()
+	q.Interactor = nil
+	q.ReferenceSolution = nil
+	q.Generator = nil
+	q.Validation = nil
+
+	if q.Checker != nil && q.Checker.Code != "" {
+		checker := *q.Checker
+		checker.Code = ""
+		q.Checker = &checker
+	}
 }
 
 func CreateQuestion(q *Question) (*mongo.InsertOneResult, error) {
 	words := strings.Split(q.Title, " ")
 	slug := strings.Join(words, "-")
 
+	// questions failing their validation are created as rejected
+	status := q.Status
+	if status == "" {
+		status = Pending
+	}
+
 	result, err := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.QUESTION_COLLECTION).InsertOne(context.TODO(), bson.M{
-		"title":        q.Title,
-		"slug":         slug,
-		"description":  q.Description,
-		"difficulty":   q.Difficulty,
-		"tags":         q.Tags,
-		"companies":    q.Companies,
-		"hints":        q.Hints,
-		"testCases":    q.TestCases,
-		"codeSnippets": q.CodeSnippets,
-		"status":       Pending,
-		"authorId":     q.AuthorID,
-		"createdAt":    time.Now(),
+		"title":             q.Title,
+		"slug":              slug,
+		"description":       q.Description,
+		"difficulty":        q.Difficulty,
+		"tags":              q.Tags,
+		"companies":         q.Companies,
+		"hints":             q.Hints,
+		"mode":              q.Mode,
+		"timeLimit":         q.TimeLimit,
+		"memoryLimit":       q.MemoryLimit,
+		"testCases":         q.TestCases,
+		"signature":         q.Signature,
+		"checker":           q.Checker,
+		"interactor":        q.Interactor,
+		"codeSnippets":      q.CodeSnippets,
+		"referenceSolution": q.ReferenceSolution,
+		"generator":         q.Generator,
+		"validation":        q.Validation,
+		"status":            status,
+		"authorId":          q.AuthorID,
+		"createdAt":         time.Now(),
 	})
 
 	if err != nil {
@@ -83,3 +278,22 @@ func CreateQuestion(q *Question) (*mongo.InsertOneResult, error) {
 
 	return result, nil
 }
+
+func GetQuestionById(id string) (*Question, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.QUESTION_COLLECTION).FindOne(context.TODO(), bson.M{"_id": objectId})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var question Question
+	if err := result.Decode(&question); err != nil {
+		return nil, err
+	}
+
+	return &question, nil
+}
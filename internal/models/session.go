@@ -0,0 +1,201 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// previousTokenHashesLimit is how many rotated refresh tokens of a session are kept to detect their reuse
+const previousTokenHashesLimit = 50
+
+// rotationGracePeriod is how long the refresh token replaced last is still accepted, for concurrent refreshes of one client
+const rotationGracePeriod = 30 * time.Second
+
+// Session is a login of a user on a device. Only the hash of its refresh token is stored,
+// the token changes on every refresh and the old ones are kept to notice when a stolen one is used.
+type Session struct {
+	ID                  string    `json:"id" bson:"_id"`
+	UserID              string    `json:"user_id" bson:"user_id"`
+	Username            string    `json:"-" bson:"username"` // users get their username once the email is verified, until then it only lives in the tokens
+	RefreshTokenHash    string    `json:"-" bson:"refresh_token_hash"`
+	PreviousTokenHashes []string  `json:"-" bson:"previous_token_hashes"`
+	UserAgent           string    `json:"user_agent" bson:"user_agent"`
+	IPAddress           string    `json:"ip_address" bson:"ip_address"` // of the last login or refresh
+	Revoked             bool      `json:"revoked" bson:"revoked"`
+	ExpiresAt           time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt           time.Time `json:"created_at" bson:"created_at"`
+	LastUsedAt          time.Time `json:"last_used_at" bson:"last_used_at"` // updated on every refresh, so it is as precise as the access token lifetime
+	RotatedAt           time.Time `json:"-" bson:"rotated_at,omitempty"`
+}
+
+// InRotationGracePeriod tells whether the token was replaced by the latest refresh of the active session only moments ago.
+// Refreshes of one browser at the same time, e.g. from two tabs, present the same token, that is not the reuse of a stolen one.
+func (s *Session) InRotationGracePeriod(tokenHash string, now time.Time) bool {
+	if s.Revoked || !now.Before(s.ExpiresAt) || len(s.PreviousTokenHashes) == 0 {
+		return false
+	}
+
+	return s.PreviousTokenHashes[len(s.PreviousTokenHashes)-1] == tokenHash && now.Sub(s.RotatedAt) < rotationGracePeriod
+}
+
+func getSessionCollection() *mongo.Collection {
+	return database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.SESSION_COLLECTION)
+}
+
+// CreateSession stores a new session and returns its id
+func CreateSession(session *Session) (string, error) {
+	result, err := getSessionCollection().InsertOne(context.TODO(), bson.M{
+		"user_id":               session.UserID,
+		"username":              session.Username,
+		"refresh_token_hash":    session.RefreshTokenHash,
+		"previous_token_hashes": []string{},
+		"user_agent":            session.UserAgent,
+		"ip_address":            session.IPAddress,
+		"revoked":               false,
+		"expires_at":            session.ExpiresAt,
+		"created_at":            time.Now(),
+		"last_used_at":          time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// RotateSession replaces the refresh token of the active session it belongs to, records where it was used from
+// and returns the updated session. mongo.ErrNoDocuments is returned when the token is not the current token of an active session.
+func RotateSession(tokenHash, newTokenHash string, expiresAt time.Time, ipAddress string) (*Session, error) {
+	filter := bson.M{
+		"refresh_token_hash": tokenHash,
+		"revoked":            false,
+		"expires_at":         bson.M{"$gt": time.Now()},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"refresh_token_hash": newTokenHash,
+			"expires_at":         expiresAt,
+			"ip_address":         ipAddress,
+			"last_used_at":       time.Now(),
+			"rotated_at":         time.Now(),
+		},
+		"$push": bson.M{
+			"previous_token_hashes": bson.M{
+				"$each":  bson.A{tokenHash},
+				"$slice": -previousTokenHashesLimit,
+			},
+		},
+	}
+
+	var session Session
+	err := getSessionCollection().FindOneAndUpdate(context.TODO(), filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetSessionByPreviousToken returns the session which already rotated the given refresh token
+func GetSessionByPreviousToken(tokenHash string) (*Session, error) {
+	var session Session
+	err := getSessionCollection().FindOne(context.TODO(), bson.M{"previous_token_hashes": tokenHash}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func GetSessionById(id string) (*Session, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	err = getSessionCollection().FindOne(context.TODO(), bson.M{"_id": objectId}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// GetActiveSessions returns the sessions of a user which were not revoked and did not expire, the most recently used first
+func GetActiveSessions(userID string) ([]Session, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	cursor, err := getSessionCollection().Find(context.TODO(), filter, options.Find().SetSort(bson.M{"last_used_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	if err := cursor.All(context.TODO(), &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func RevokeSession(id string) error {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = getSessionCollection().UpdateOne(context.TODO(), bson.M{"_id": objectId}, bson.M{
+		"$set": bson.M{"revoked": true},
+	})
+	return err
+}
+
+// RevokeUserSessions revokes the active sessions of a user but the given one (if any) and returns their ids
+func RevokeUserSessions(userID, exceptSessionID string) ([]string, error) {
+	sessions, err := GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	objectIds := []primitive.ObjectID{}
+	for _, session := range sessions {
+		if session.ID == exceptSessionID {
+			continue
+		}
+
+		objectId, err := primitive.ObjectIDFromHex(session.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, session.ID)
+		objectIds = append(objectIds, objectId)
+	}
+
+	if len(objectIds) == 0 {
+		return ids, nil
+	}
+
+	_, err = getSessionCollection().UpdateMany(context.TODO(), bson.M{"_id": bson.M{"$in": objectIds}}, bson.M{
+		"$set": bson.M{"revoked": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
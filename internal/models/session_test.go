@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionInRotationGracePeriod(t *testing.T) {
+	now := time.Now()
+
+	active := func(rotatedAgo time.Duration) Session {
+		return Session{
+			RefreshTokenHash:    "current",
+			PreviousTokenHashes: []string{"first", "second", "last"},
+			ExpiresAt:           now.Add(time.Hour),
+			RotatedAt:           now.Add(-rotatedAgo),
+		}
+	}
+
+	revoked := active(time.Second)
+	revoked.Revoked = true
+
+	expired := active(time.Second)
+	expired.ExpiresAt = now.Add(-time.Minute)
+
+	neverRotated := Session{RefreshTokenHash: "current", PreviousTokenHashes: []string{}, ExpiresAt: now.Add(time.Hour)}
+
+	// sessions rotated before the rotation time was stored have none
+	withoutRotationTime := active(0)
+	withoutRotationTime.RotatedAt = time.Time{}
+
+	tests := []struct {
+		name      string
+		session   Session
+		tokenHash string
+		want      bool
+	}{
+		{"token replaced just now", active(time.Second), "last", true},
+		{"token replaced at the end of the grace period", active(rotationGracePeriod - time.Millisecond), "last", true},
+		{"token replaced before the grace period", active(rotationGracePeriod), "last", false},
+		{"token replaced by an earlier refresh", active(time.Second), "second", false},
+		{"unknown token", active(time.Second), "other", false},
+		{"revoked session", revoked, "last", false},
+		{"expired session", expired, "last", false},
+		{"session never rotated", neverRotated, "current", false},
+		{"session without rotation time", withoutRotationTime, "last", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.InRotationGracePeriod(tt.tokenHash, now); got != tt.want {
+				t.Errorf("InRotationGracePeriod(%q) = %v, want %v", tt.tokenHash, got, tt.want)
+			}
+		})
+	}
+}
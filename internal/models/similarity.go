@@ -0,0 +1,77 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MatchRegion is a range of lines (1-based, inclusive) of a submission which matches the other submission of a pair
+type MatchRegion struct {
+	StartLine int `json:"start_line" bson:"start_line"`
+	EndLine   int `json:"end_line" bson:"end_line"`
+}
+
+// SimilarityPair is the most similar pair of accepted submissions of two users for a question.
+// UserA is always the smaller user id, so a pair of users has a single document.
+type SimilarityPair struct {
+	ID          string        `json:"id" bson:"_id,omitempty"`
+	QuestionID  string        `json:"question_id" bson:"question_id"`
+	Language    string        `json:"language" bson:"language"`
+	UserA       string        `json:"user_a" bson:"user_a"`
+	SubmissionA string        `json:"submission_a" bson:"submission_a"`
+	MatchesA    []MatchRegion `json:"matches_a" bson:"matches_a"`
+	UserB       string        `json:"user_b" bson:"user_b"`
+	SubmissionB string        `json:"submission_b" bson:"submission_b"`
+	MatchesB    []MatchRegion `json:"matches_b" bson:"matches_b"`
+	Score       float64       `json:"score" bson:"score"` // share of the fingerprints of the smaller submission found in the other one
+	DetectedAt  time.Time     `json:"detected_at" bson:"detected_at"`
+}
+
+// SaveSimilarityPair stores the pair unless the two users already have a pair for the question with a higher score
+func SaveSimilarityPair(pair *SimilarityPair) error {
+	collection := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.SIMILARITY_COLLECTION)
+
+	filter := bson.M{
+		"question_id": pair.QuestionID,
+		"user_a":      pair.UserA,
+		"user_b":      pair.UserB,
+	}
+
+	var existing SimilarityPair
+	err := collection.FindOne(context.TODO(), filter).Decode(&existing)
+	if err == nil && existing.Score >= pair.Score {
+		return nil
+	}
+
+	_, err = collection.ReplaceOne(context.TODO(), filter, pair, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetSimilarityPairs returns the pairs of a question with at least the given score, the most similar first
+func GetSimilarityPairs(questionID string, minScore float64) ([]SimilarityPair, error) {
+	options := options.Find().SetSort(bson.M{"score": -1})
+	cursor, err := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.SIMILARITY_COLLECTION).Find(
+		context.TODO(),
+		bson.M{
+			"question_id": questionID,
+			"score":       bson.M{"$gte": minScore},
+		},
+		options,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := []SimilarityPair{}
+	if err := cursor.All(context.TODO(), &pairs); err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
@@ -6,11 +6,20 @@ import (
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+type Role string
+
+const (
+	UserRole  Role = "user"
+	AdminRole Role = "admin"
+)
+
 type Stats struct {
 	QuestionsSubmitted int `json:"questions_submitted" bson:"questions_submitted"`
+	QuestionsAccepted int `json:"questions_accepted" bson:"questions_accepted"`
 	QuestionsCreated int `json:"questions_created" bson:"questions_created"`
 	BlogsCreated int `json:"blogs_created" bson:"blogs_created"`
 	ChallengesCreated int `json:"challenges_created" bson:"challenges_created"`
@@ -23,12 +32,16 @@ type User struct {
 	Username                  string    `json:"username" bson:"username"`
 	Email                     string    `json:"email" bson:"email"`
 	Password                  string    `json:"password,omitempty" bson:"password"`
+	Role                      Role      `json:"role,omitempty" bson:"role,omitempty"` // users created before roles have none and are plain users
 	IsEmailVerified           bool      `json:"is_email_verified" bson:"is_email_verified"`
 	VerificationCode          string    `json:"verification_code" bson:"verification_code"`
 	VerificationCodeExpiresAt time.Time `json:"verification_code_expires_at" bson:"verification_code_expires_at"`
+	PasswordResetTokenHash      string    `json:"-" bson:"password_reset_token_hash,omitempty"`
+	PasswordResetTokenExpiresAt time.Time `json:"-" bson:"password_reset_token_expires_at,omitempty"`
 	CreatedAt                 time.Time `json:"created_at" bson:"created_at"`
 	Stats                     Stats     `json:"stats" bson:"stats"`
 	QuestionsSubmitted          []string       `json:"questions_submitted" bson:"questions_submitted"` // list of questions submitted
+	QuestionsAccepted           []string       `json:"questions_accepted" bson:"questions_accepted,omitempty"` // list of questions accepted at least once
 	ChallengesTaken []string       `json:"challenges_taken" bson:"challenges_taken"` // list of challenge ids
 }
 
@@ -38,6 +51,7 @@ func CreateUser(user *User) (*mongo.InsertOneResult, error) {
 		"username":                     user.Username,
 		"email":                        user.Email,
 		"password":                     user.Password,
+		"role":                         UserRole,
 		"is_email_verified":            false,
 		"verification_code":            user.VerificationCode,
 		"verification_code_expires_at": time.Now().Add(time.Hour),
@@ -52,3 +66,103 @@ func CreateUser(user *User) (*mongo.InsertOneResult, error) {
 
 	return result, nil
 }
+
+// AddAcceptedQuestion counts an accepted question for a user, a question accepted again is not counted twice
+func AddAcceptedQuestion(userID, questionID string) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	// the filter makes it a no-op when the question is already in the list, even for concurrent submissions
+	_, err = database.UserCollection.UpdateOne(context.Background(), bson.M{
+		"_id":                objectId,
+		"questions_accepted": bson.M{"$ne": questionID},
+	}, bson.M{
+		"$addToSet": bson.M{"questions_accepted": questionID},
+		"$inc":      bson.M{"stats.questions_accepted": 1},
+	})
+	return err
+}
+
+// RemoveAcceptedQuestion takes back the count of an accepted question, e.g. when the question is deleted
+func RemoveAcceptedQuestion(userID, questionID string) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	// the filter makes it a no-op when the question was never accepted, so the stat only goes down once
+	_, err = database.UserCollection.UpdateOne(context.Background(), bson.M{
+		"_id":                objectId,
+		"questions_accepted": questionID,
+	}, bson.M{
+		"$pull": bson.M{"questions_accepted": questionID},
+		"$inc":  bson.M{"stats.questions_accepted": -1},
+	})
+	return err
+}
+
+func GetUserById(id string) (*User, error) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := database.UserCollection.FindOne(context.Background(), bson.M{"_id": objectId})
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	var user User
+	if err := result.Decode(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SetPasswordResetToken stores the hash of a new password reset token of a user, replacing the previous one
+func SetPasswordResetToken(userID, tokenHash string, expiresAt time.Time) error {
+	objectId, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.UserCollection.UpdateOne(context.Background(), bson.M{"_id": objectId}, bson.M{
+		"$set": bson.M{
+			"password_reset_token_hash":       tokenHash,
+			"password_reset_token_expires_at": expiresAt,
+		},
+	})
+	return err
+}
+
+// ResetPassword sets the password of the user the unexpired reset token belongs to and spends the token.
+// mongo.ErrNoDocuments is returned when no user has the token.
+func ResetPassword(tokenHash, hashedPassword string) (*User, error) {
+	filter := bson.M{
+		"password_reset_token_hash":       tokenHash,
+		"password_reset_token_expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"password": hashedPassword},
+		"$unset": bson.M{
+			"password_reset_token_hash":       "",
+			"password_reset_token_expires_at": "",
+		},
+	}
+
+	var user User
+	err := database.UserCollection.FindOneAndUpdate(context.Background(), filter, update).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == AdminRole
+}
@@ -5,15 +5,21 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var Conn *amqp.Connection
 var Ch *amqp.Channel
 var Q *amqp.Queue
 
+// judge queue gets its own channel so that its prefetch does not affect the email consumer
+var JudgeCh *amqp.Channel
+var JudgeQ *amqp.Queue
+
 func InitializeRabbitMQ() error {
 	// create a connection
 	conn, err := amqp.Dial(config.Config.RABBITMQ_URL)
 	if err != nil {
 		return err
 	}
+	Conn = conn
 
 	// create a channel
 	ch, err := conn.Channel()
@@ -29,5 +35,18 @@ func InitializeRabbitMQ() error {
 	}
 	Q = &q
 
+	// create the judge channel and queue, durable so that queued submissions survive a broker restart
+	judgeCh, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	JudgeCh = judgeCh
+
+	judgeQ, err := judgeCh.QueueDeclare(config.Config.JUDGE_QUEUE_NAME, true, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	JudgeQ = &judgeQ
+
 	return nil
 }
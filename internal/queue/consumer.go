@@ -2,8 +2,11 @@ package queue
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/services"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,7 +29,12 @@ func StartConsumer() error {
 				logrus.Errorf("Error unmarshalling the message: %v", err)
 			}
 
-			err = services.SendEmail(payload.Email, payload.Username, payload.Code)
+			switch payload.Type {
+			case constants.EMAIL_TYPE_PASSWORD_RESET:
+				err = services.SendPasswordResetEmail(payload.Email, payload.Username, payload.Token)
+			default:
+				err = services.SendEmail(payload.Email, payload.Username, payload.Code)
+			}
 			if err != nil {
 				logrus.Errorf("Error sending email: %v", err)
 			} else {
@@ -39,3 +47,58 @@ func StartConsumer() error {
 
 	return nil
 }
+
+// StartJudgeConsumer starts the judge workers, each worker judges one submission at a time
+func StartJudgeConsumer() error {
+	// without a worker nothing is judged, and a prefetch count of 0 would not limit the deliveries at all
+	workers := config.Config.JUDGE_WORKERS
+	if workers < 1 {
+		return fmt.Errorf("invalid number of judge workers: %d", workers)
+	}
+
+	err := JudgeCh.Qos(workers, 0, false)
+	if err != nil {
+		return err
+	}
+
+	messages, err := JudgeCh.Consume(JudgeQ.Name, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for message := range messages {
+				var payload SubmissionPayload
+				err := json.Unmarshal(message.Body, &payload)
+				if err != nil {
+					logrus.Errorf("Error unmarshalling the submission message: %v", err)
+					message.Ack(false)
+					continue
+				}
+
+				err = services.JudgeSubmission(payload.SubmissionID)
+				if err != nil && services.IsRetryableJudgeError(err) && !message.Redelivered {
+					// judged once more before giving up, a submission judged twice only stores the later outcome
+					logrus.Warnf("Error judging submission %s, requeueing it: %v", payload.SubmissionID, err)
+					message.Nack(false, true)
+					continue
+				}
+
+				if err != nil {
+					// the submission must not stay queued or running, nobody would ever end it
+					err = services.FailSubmission(payload.SubmissionID, err)
+					if err != nil {
+						logrus.Errorf("Error failing submission %s: %v", payload.SubmissionID, err)
+					}
+				} else {
+					logrus.Infof("Submission %s judged", payload.SubmissionID)
+				}
+
+				message.Ack(false)
+			}
+		}()
+	}
+
+	return nil
+}
@@ -8,10 +8,13 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// EmailVerificationPayload is an email to send, Type tells which one (messages without one are verification emails)
 type EmailVerificationPayload struct {
+	Type     string `json:"type,omitempty"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
-	Code     string `json:"code"`
+	Code     string `json:"code,omitempty"`
+	Token    string `json:"token,omitempty"` // password reset token
 }
 
 func StartProducer(payload EmailVerificationPayload) error {
@@ -36,3 +39,31 @@ func StartProducer(payload EmailVerificationPayload) error {
 
 	return nil
 }
+
+type SubmissionPayload struct {
+	SubmissionID string `json:"submission_id"`
+}
+
+func PublishSubmission(payload SubmissionPayload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	err = JudgeCh.PublishWithContext(ctx, "", JudgeQ.Name, false, false,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
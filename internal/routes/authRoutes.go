@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/handlers"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/middlewares"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
@@ -12,7 +14,13 @@ func AuthRoutes(r *gin.Engine) {
 	authGroup.POST(constants.AUTH_API_REGISTER_ENDPOINT, handlers.Register)
 	authGroup.POST(constants.AUTH_API_LOGIN_ENDPOINT, handlers.Login)
 	authGroup.POST(constants.AUTH_API_LOGOUT_ENDPOINT, middlewares.Authorization(), handlers.Logout)
+	authGroup.POST(constants.AUTH_API_LOGOUT_ALL_ENDPOINT, middlewares.Authorization(), handlers.LogoutAll)
+	authGroup.POST(constants.AUTH_API_REFRESH_ENDPOINT, handlers.RefreshToken)
+	authGroup.GET(constants.AUTH_API_GET_SESSIONS_ENDPOINT, middlewares.Authorization(), handlers.GetSessions)
+	authGroup.DELETE(constants.AUTH_API_REVOKE_SESSION_ENDPOINT, middlewares.Authorization(), handlers.RevokeSession)
+	authGroup.DELETE(constants.AUTH_API_REVOKE_OTHER_SESSIONS_ENDPOINT, middlewares.Authorization(), handlers.RevokeOtherSessions)
 	authGroup.POST(constants.AUTH_API_EMAIL_VERIFY_ENDPOINT, middlewares.Authorization(), handlers.VerifyEmail)
-	authGroup.POST(constants.AUTH_API_FORGOT_PASSWORD_ENDPOINT, handlers.ForgotPassword)
+	authGroup.POST(constants.AUTH_API_FORGOT_PASSWORD_ENDPOINT, middlewares.RateLimiter(5, time.Hour), handlers.ForgotPassword)
+	authGroup.POST(constants.AUTH_API_RESET_PASSWORD_ENDPOINT, handlers.ResetPassword)
 	authGroup.POST(constants.AUTH_API_RESEND_VERIFICATION_CODE_ENDPOINT, middlewares.Authorization(), handlers.ResendVerificationCodeViaEmail)
 }
@@ -22,4 +22,6 @@ func QuestionRoutes(r *gin.Engine) {
 	questionRouteGroup.DELETE(constants.QUESTION_API_DELETE_ENDPOINT, handlers.DeleteQuestion)
 	questionRouteGroup.GET(constants.QUESTION_API_GET_BY_USER_ENDPOINT, handlers.GetQuestionsByUser)
 	questionRouteGroup.GET(constants.QUESTIONS_API_GET_SUBMISSIONS_ON_A_QUESTION_ENDPOINT, handlers.GetSubmissionsOnAQuestion)
+	questionRouteGroup.POST(constants.QUESTION_API_GENERATE_TEST_CASES_ENDPOINT, middlewares.RateLimiter(10, time.Hour), handlers.GenerateTestCases)
+	questionRouteGroup.GET(constants.QUESTION_API_GET_SIMILAR_SUBMISSIONS_ENDPOINT, handlers.GetSimilarSubmissions)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/handlers"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/middlewares"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+	"github.com/gin-gonic/gin"
+)
+
+func SubmissionRoutes(r *gin.Engine) {
+	submissionRouteGroup := r.Group(constants.SUBMISSION_API_BASE_ENDPOINT)
+
+	submissionRouteGroup.Use(middlewares.Authorization())
+
+	submissionRouteGroup.GET(constants.SUBMISSION_API_GET_BY_ID_ENDPOINT, handlers.GetSubmissionById)
+	submissionRouteGroup.GET(constants.SUBMISSION_API_EVENTS_ENDPOINT, handlers.StreamSubmissionEvents)
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+// DefaultTolerance is used by the float checker when the question does not set one
+const DefaultTolerance = 1e-6
+
+// checkerTimeout limits compiling a custom checker program and every run of it
+const checkerTimeout = 10 * time.Second
+
+// outputChecker decides with the checker of a question whether the outputs of a run are correct.
+// A custom checker program is compiled once and run for every output.
+type outputChecker struct {
+	checker *models.Checker
+	session ExecutionSession
+}
+
+// prepareOutputChecker compiles the custom checker of a question, the other checkers need nothing
+func prepareOutputChecker(ctx context.Context, checker *models.Checker) (*outputChecker, error) {
+	if checker == nil || checker.Type != models.CustomChecker {
+		return &outputChecker{checker: checker}, nil
+	}
+
+	session, result, err := CodeExecutor.Prepare(ctx, ExecutionRequest{
+		Language: strings.ToLower(checker.Language),
+		Code:     checker.Code,
+		Limits:   DefaultLimits,
+		Timeout:  checkerTimeout,
+	})
+	if err != nil {
+		return nil, &retryableError{fmt.Errorf("failed to prepare the checker: %w", err)}
+	}
+	if result != nil {
+		return nil, fmt.Errorf("checker failed with %s: %s", result.Verdict, result.Stderr)
+	}
+
+	return &outputChecker{checker: checker, session: session}, nil
+}
+
+// Check decides whether the output of a test case is correct
+func (c *outputChecker) Check(ctx context.Context, input, output, expected string) (bool, error) {
+	if c.session != nil {
+		return c.runCustomChecker(ctx, input, output, expected)
+	}
+
+	return checkOutput(c.checker, output, expected)
+}
+
+func (c *outputChecker) Close() {
+	if c.session != nil {
+		c.session.Close()
+	}
+}
+
+// checkOutput compares an output with the expected output by a checker which is not a program
+func checkOutput(checker *models.Checker, output, expected string) (bool, error) {
+	if checker == nil {
+		return checkExact(output, expected), nil
+	}
+
+	switch checker.Type {
+	case models.ExactChecker, "":
+		return checkExact(output, expected), nil
+	case models.TokenChecker:
+		return slices.Equal(strings.Fields(output), strings.Fields(expected)), nil
+	case models.FloatChecker:
+		tolerance := checker.Tolerance
+		if tolerance <= 0 {
+			tolerance = DefaultTolerance
+		}
+		return checkFloat(output, expected, tolerance), nil
+	case models.UnorderedChecker:
+		return checkUnordered(output, expected), nil
+	}
+
+	return false, fmt.Errorf("unsupported checker: %s", checker.Type)
+}
+
+func checkExact(output, expected string) bool {
+	return strings.TrimSpace(output) == strings.TrimSpace(expected)
+}
+
+func decodeJSON(text string) (interface{}, bool) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, false
+	}
+	return value, true
+}
+
+func floatsEqual(a, b, tolerance float64) bool {
+	// absolute error for small numbers and relative error for large ones
+	return math.Abs(a-b) <= tolerance*math.Max(1, math.Abs(b))
+}
+
+// checkFloat compares JSON values structurally, or whitespace separated tokens, with numbers compared by tolerance
+func checkFloat(output, expected string, tolerance float64) bool {
+	outputValue, outputOk := decodeJSON(output)
+	expectedValue, expectedOk := decodeJSON(expected)
+	if outputOk && expectedOk {
+		return jsonEqualWithTolerance(outputValue, expectedValue, tolerance)
+	}
+
+	outputTokens, expectedTokens := strings.Fields(output), strings.Fields(expected)
+	if len(outputTokens) != len(expectedTokens) {
+		return false
+	}
+
+	for i := range outputTokens {
+		a, errA := strconv.ParseFloat(outputTokens[i], 64)
+		b, errB := strconv.ParseFloat(expectedTokens[i], 64)
+		if errA == nil && errB == nil {
+			if !floatsEqual(a, b, tolerance) {
+				return false
+			}
+		} else if outputTokens[i] != expectedTokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func jsonEqualWithTolerance(output, expected interface{}, tolerance float64) bool {
+	switch e := expected.(type) {
+	case json.Number:
+		o, ok := output.(json.Number)
+		if !ok {
+			return false
+		}
+		a, errA := o.Float64()
+		b, errB := e.Float64()
+		return errA == nil && errB == nil && floatsEqual(a, b, tolerance)
+	case []interface{}:
+		o, ok := output.([]interface{})
+		if !ok || len(o) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !jsonEqualWithTolerance(o[i], e[i], tolerance) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		o, ok := output.(map[string]interface{})
+		if !ok || len(o) != len(e) {
+			return false
+		}
+		for key := range e {
+			if !jsonEqualWithTolerance(o[key], e[key], tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return output == expected
+}
+
+// checkUnordered compares the elements of JSON arrays, or whitespace separated tokens, as multisets
+func checkUnordered(output, expected string) bool {
+	outputValue, outputOk := decodeJSON(output)
+	expectedValue, expectedOk := decodeJSON(expected)
+
+	outputItems, outputIsArray := outputValue.([]interface{})
+	expectedItems, expectedIsArray := expectedValue.([]interface{})
+
+	var outputKeys, expectedKeys []string
+	if outputOk && expectedOk && outputIsArray && expectedIsArray {
+		outputKeys = canonicalElements(outputItems)
+		expectedKeys = canonicalElements(expectedItems)
+	} else {
+		outputKeys = strings.Fields(output)
+		expectedKeys = strings.Fields(expected)
+	}
+
+	slices.Sort(outputKeys)
+	slices.Sort(expectedKeys)
+	return slices.Equal(outputKeys, expectedKeys)
+}
+
+func canonicalElements(items []interface{}) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		key, _ := json.Marshal(item)
+		keys = append(keys, string(key))
+	}
+	return keys
+}
+
+// runCustomChecker runs the checker program of the author in the sandbox, exit code 0 accepts the output
+func (c *outputChecker) runCustomChecker(ctx context.Context, input, output, expected string) (bool, error) {
+	var stdin bytes.Buffer
+	err := json.NewEncoder(&stdin).Encode(map[string]string{
+		"input":    input,
+		"output":   output,
+		"expected": expected,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	result, err := c.session.Run(ctx, RunRequest{
+		Stdin: stdin.String(),
+	})
+	if err != nil {
+		return false, &retryableError{fmt.Errorf("failed to run the checker: %w", err)}
+	}
+
+	switch result.Verdict {
+	case models.OK:
+		return true, nil
+	case models.RuntimeError:
+		// any other exit code rejects the output
+		return false, nil
+	}
+
+	return false, fmt.Errorf("checker failed with %s: %s", result.Verdict, result.Stderr)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+func TestCheckOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		checker  *models.Checker
+		output   string
+		expected string
+		want     bool
+	}{
+		{"no checker compares exactly", nil, "1 2\n", "1 2", true},
+		{"no checker rejects other spacing", nil, "1  2", "1 2", false},
+		{"exact", &models.Checker{Type: models.ExactChecker}, " [1,2] ", "[1,2]", true},
+		{"exact rejects other output", &models.Checker{Type: models.ExactChecker}, "[1, 2]", "[1,2]", false},
+		{"empty type is exact", &models.Checker{}, "abc", "abc", true},
+		{"token ignores whitespace", &models.Checker{Type: models.TokenChecker}, "1\n2   3\n", "1 2 3", true},
+		{"token rejects other tokens", &models.Checker{Type: models.TokenChecker}, "1 2", "1 2 3", false},
+		{"float with default tolerance", &models.Checker{Type: models.FloatChecker}, "0.3333333", "0.33333333", true},
+		{"float with tolerance", &models.Checker{Type: models.FloatChecker, Tolerance: 0.01}, "3.14", "3.141", true},
+		{"float beyond tolerance", &models.Checker{Type: models.FloatChecker, Tolerance: 0.0001}, "3.14", "3.141", false},
+		{"unordered", &models.Checker{Type: models.UnorderedChecker}, "[3,1,2]", "[1,2,3]", true},
+		{"unordered rejects other elements", &models.Checker{Type: models.UnorderedChecker}, "[1,1,2]", "[1,2,2]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkOutput(tt.checker, tt.output, tt.expected)
+			if err != nil {
+				t.Fatalf("checkOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("checkOutput(%q, %q) = %v, want %v", tt.output, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOutputUnsupportedChecker(t *testing.T) {
+	_, err := checkOutput(&models.Checker{Type: "regex"}, "a", "a")
+	if err == nil {
+		t.Fatal("checkOutput() with an unsupported checker did not fail")
+	}
+}
+
+func TestCheckFloat(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		expected  string
+		tolerance float64
+		want      bool
+	}{
+		{"equal numbers", "2.5", "2.5", 1e-6, true},
+		{"absolute error for small numbers", "0.0000001", "0", 1e-6, true},
+		{"relative error for large numbers", "1000000.5", "1000000", 1e-6, true},
+		{"too far off", "1.1", "1", 1e-6, false},
+		{"integer and float", "2", "2.0000000001", 1e-6, true},
+		{"nested JSON", `{"a":[1.0000001,2]}`, `{"a":[1,2]}`, 1e-6, true},
+		{"JSON of another shape", `[1,2]`, `[1,2,3]`, 1e-6, false},
+		{"JSON with other keys", `{"a":1}`, `{"b":1}`, 1e-6, false},
+		{"JSON strings compared exactly", `["a",1.0000001]`, `["a",1]`, 1e-6, true},
+		{"tokens with numbers", "x 1.0000001\ny 2", "x 1 y 2", 1e-6, true},
+		{"tokens with other words", "x 1", "y 1", 1e-6, false},
+		{"tokens of other length", "1 2", "1", 1e-6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkFloat(tt.output, tt.expected, tt.tolerance); got != tt.want {
+				t.Errorf("checkFloat(%q, %q, %g) = %v, want %v", tt.output, tt.expected, tt.tolerance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUnordered(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+		want     bool
+	}{
+		{"same order", "[1,2,3]", "[1,2,3]", true},
+		{"other order", "[3,2,1]", "[1,2,3]", true},
+		{"duplicates count", "[1,2]", "[1,1,2]", false},
+		{"nested arrays are elements", "[[1,2],[3]]", "[[3],[1,2]]", true},
+		{"nested arrays keep their order", "[[2,1]]", "[[1,2]]", false},
+		{"objects as elements", `[{"a":1},{"b":2}]`, `[{"b":2},{"a":1}]`, true},
+		{"tokens", "c a b", "a\nb c", true},
+		{"tokens differ", "a b", "a c", false},
+		{"array and tokens", "[1,2]", "1 2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkUnordered(tt.output, tt.expected); got != tt.want {
+				t.Errorf("checkUnordered(%q, %q) = %v, want %v", tt.output, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
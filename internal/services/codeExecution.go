@@ -5,97 +5,23 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 )
 
-type Response struct {
-	Input string `json:"input,omitempty"`
-	Output      string `json:"output,omitempty"`
-	Expected string `json:"expected,omitempty"`
-	Result   bool   `json:"result,omitempty"`
-}
-
-func cleanUpTempDir(dir string) error {
-	err := os.RemoveAll(dir)
-	if err != nil {
-		return fmt.Errorf("failed to delete temp dir %s: %w", dir, err)
-	}
-
-	return nil
-}
-
-func removeDockerContainer(client *client.Client, contID string) error {
-	// Remove the main container
-	err := client.ContainerRemove(context.Background(), contID, container.RemoveOptions{
-		Force: true,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete main container %s: %w", contID, err)
-	}
-
-	// Remove containers which are left in "created" state
-	containers, err := client.ContainerList(context.Background(), container.ListOptions{
-		Filters: filters.NewArgs(
-			filters.Arg("status", "created"),
-		),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to list created containers: %w", err)
-	}
-
-	// Loop and remove these containers
-	for _, cont := range containers {
-		err := client.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{
-			Force: true,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to delete container %s: %w", cont.ID, err)
-		}
-	}
-
-	return nil
-}
-
-func removeDockerImage(client *client.Client, imageName string) error {
-	// Remove the main image
-	_, err := client.ImageRemove(context.Background(), imageName, image.RemoveOptions{
-		Force: true,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete main image %s: %w", imageName, err)
-	}
-
-	images, err := client.ImageList(context.Background(), image.ListOptions{
-		Filters: filters.NewArgs(
-			filters.Arg("dangling", "true"),
-		),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to list dangling images: %w", err)
-	}
+// languages supported by the sandbox runner
+var supportedLanguages = []string{"python", "javascript", "go", "cpp", "java"}
 
-	// Loop and remove each dangling image
-	for _, img := range images {
-		_, err := client.ImageRemove(context.Background(), img.ID, image.RemoveOptions{
-			Force: true,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to delete dangling image %s: %w", img.ID, err)
-		}
-	}
-
-	return nil
+// limits of the sandbox containers which differ from DefaultLimits.
+// Every thread counts against the pids limit and the compilers and the JVM run a lot of them.
+var languageLimits = map[string]ExecutionLimits{
+	"go":   {Processes: 256},
+	"java": {Processes: 256, MemoryBytes: 1024 * 1024 * 1024},
 }
 
 func getCodeFileName(language string) (string, error) {
@@ -119,6 +45,9 @@ func getCodeFileName(language string) (string, error) {
 	return fileName, nil
 }
 
+// getDockerfileContent returns the Dockerfile for the toolchain image of a language.
+// The image only contains the toolchain and the time utility used to measure runs,
+// the code is copied into a warm container per run.
 func getDockerfileContent(language string) (string, error) {
 	var Dockerfile string
 
@@ -126,38 +55,35 @@ func getDockerfileContent(language string) (string, error) {
 	case "python":
 		Dockerfile = `
 			FROM python:3.12-slim
-			WORKDIR /app
-			COPY . /app
-			CMD ["python", "main.py"]
+			RUN apt-get update && apt-get install -y --no-install-recommends time && rm -rf /var/lib/apt/lists/*
+			WORKDIR /sandbox
+			CMD ["tail", "-f", "/dev/null"]
 			`
 	case "javascript":
 		Dockerfile = `
 			FROM node:20-slim
-			WORKDIR /app
-			COPY . /app
-			CMD ["node", "main.js"]
+			RUN apt-get update && apt-get install -y --no-install-recommends time && rm -rf /var/lib/apt/lists/*
+			WORKDIR /sandbox
+			CMD ["tail", "-f", "/dev/null"]
 			`
 	case "go":
 		Dockerfile = `
 			FROM golang:alpine
-			WORKDIR /app
-			COPY . /app
-			CMD ["go", "run", "main.go"]
+			WORKDIR /sandbox
+			CMD ["tail", "-f", "/dev/null"]
 			`
 	case "cpp":
 		Dockerfile = `
 			FROM alpine:latest
 			RUN apk add --no-cache g++ libstdc++
-			WORKDIR /app
-			COPY . /app
-			CMD ["sh", "-c", "g++ -o main main.cpp && ./main"]
+			WORKDIR /sandbox
+			CMD ["tail", "-f", "/dev/null"]
 			`
 	case "java":
 		Dockerfile = `
 			FROM openjdk:17-alpine
-			WORKDIR /app
-			COPY . /app
-			CMD ["sh", "-c", "javac Main.java && java Main"]
+			WORKDIR /sandbox
+			CMD ["tail", "-f", "/dev/null"]
 			`
 	default:
 		return "", fmt.Errorf("unsupported language: %s", language)
@@ -166,6 +92,47 @@ func getDockerfileContent(language string) (string, error) {
 	return Dockerfile, nil
 }
 
+// getCompileCommand returns the command which compiles the code file inside the job directory.
+// Interpreted languages have no compile step and get a nil command.
+func getCompileCommand(language string) ([]string, error) {
+	var command []string
+
+	switch language {
+	case "python", "javascript":
+		command = nil
+	case "go":
+		command = []string{"go", "build", "-o", "main", "main.go"}
+	case "cpp":
+		command = []string{"g++", "-O2", "-o", "main", "main.cpp"}
+	case "java":
+		command = []string{"javac", "Main.java"}
+	default:
+		return nil, fmt.Errorf("unsupported language: %s", language)
+	}
+
+	return command, nil
+}
+
+// getRunCommand returns the command which runs the (compiled) code inside the job directory
+func getRunCommand(language string) ([]string, error) {
+	var command []string
+
+	switch language {
+	case "python":
+		command = []string{"python3", "main.py"}
+	case "javascript":
+		command = []string{"node", "main.js"}
+	case "go", "cpp":
+		command = []string{"./main"}
+	case "java":
+		command = []string{"java", "Main"}
+	default:
+		return nil, fmt.Errorf("unsupported language: %s", language)
+	}
+
+	return command, nil
+}
+
 func getImageName(language string) string {
 	var imageName string
 
@@ -189,56 +156,62 @@ func getImageName(language string) string {
 	return imageName
 }
 
-func buildImageFromDockerfile(client *client.Client, tags []string, dockerfilePath string) error {
-	ctx := context.Background()
-
+// createTarArchive packs the given files (name -> content) into an in-memory tar archive
+func createTarArchive(files map[string][]byte) (*bytes.Buffer, error) {
 	buffer := new(bytes.Buffer)
 	tarWriter := tar.NewWriter(buffer)
-	defer tarWriter.Close()
 
-	// read dockerfile
-	dockerfileReader, err := os.Open(dockerfilePath)
-	if err != nil {
-		return err
-	}
-	dockerfileContent, err := io.ReadAll(dockerfileReader)
-	if err != nil {
-		return err
-	}
+	for name, content := range files {
+		tarHeader := &tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		}
 
-	// write dockerfile content to tar file
-	tarHeader := &tar.Header{
-		Name: dockerfilePath,
-		Size: int64(len(dockerfileContent)),
+		err := tarWriter.WriteHeader(tarHeader)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tarWriter.Write(content)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = tarWriter.WriteHeader(tarHeader)
+	err := tarWriter.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tarWriter.Write(dockerfileContent)
+	return buffer, nil
+}
+
+func buildImageFromDockerfile(client *client.Client, tags []string, dockerfileContent string) error {
+	ctx := context.Background()
+
+	buildContext, err := createTarArchive(map[string][]byte{
+		"Dockerfile": []byte(dockerfileContent),
+	})
 	if err != nil {
 		return err
 	}
 
-	dockerTarFileReader := bytes.NewReader(buffer.Bytes())
-
 	buildOptions := types.ImageBuildOptions{
-		Context: dockerTarFileReader,
-		Dockerfile: dockerfilePath,
+		Dockerfile: "Dockerfile",
 		Tags: tags,
+		Remove: true,
 	}
 
 	// Build the actual image
-	imageBuildResponse, err := client.ImageBuild(ctx, dockerTarFileReader, buildOptions)
+	imageBuildResponse, err := client.ImageBuild(ctx, buildContext, buildOptions)
 	if err != nil {
 		return err
 	}
 	defer imageBuildResponse.Body.Close()
 
-	// Read the STDOUT from the build process
-	_, err = io.Copy(os.Stdout, imageBuildResponse.Body)
+	// Read the STDOUT from the build process, this also surfaces build errors
+	err = jsonmessage.DisplayJSONMessagesStream(imageBuildResponse.Body, os.Stdout, 0, false, nil)
 	if err != nil {
 		return err
 	}
@@ -246,146 +219,30 @@ func buildImageFromDockerfile(client *client.Client, tags []string, dockerfilePa
 	return nil
 }
 
-func runContainer(client *client.Client, dir, dockerImageName, containerName string) (string, error) {
-	// context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-	
-	// create container
-	cont, err := client.ContainerCreate(
-		ctx, 
-		&container.Config{
-			Image: dockerImageName,
-		},
-		&container.HostConfig{
-			// mount the volume [temp dir -> docker container] to run e.g. python main.py 
-			Mounts: []mount.Mount{
-				{
-					Type:   mount.TypeBind,
-					Source: dir,
-					Target: "/app",
-				},
-			},
-			// limit resources
-			Resources: container.Resources{
-				Memory: 512 * 1024 * 1024,
-				CPUQuota: 50000,
-			},
-		},
-		nil,
-		nil,
-		containerName,
-	)
-	if err != nil {
-		return "", err
-	}
-	
-	defer removeDockerContainer(client, cont.ID)
-
-	// start the container
-	err = client.ContainerStart(ctx, cont.ID, container.StartOptions{})
-	if err != nil {
-		return "", err
-	}
-
-	statusCh, errCh := client.ContainerWait(ctx, cont.ID, container.WaitConditionNotRunning)
-	select {
-		case err := <-errCh:
-			return "", fmt.Errorf("error while waiting for container: %w", err)
-		case <-ctx.Done():
-			// Timeout occured, stop the container
-			err = client.ContainerStop(ctx, cont.ID, container.StopOptions{})
-			if err != nil {
-				return "", fmt.Errorf("failed to stop container after timeout: %w", err)
-			}
-			return "", fmt.Errorf("execution timed out")
-		case <-statusCh:
-	}
-
-	// get the logs from container
-	out, err := client.ContainerLogs(ctx, cont.ID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-	})
-	if err != nil {
-		return "", err
-	}
-
-	output, err := io.ReadAll(out)
-	if err != nil {
-		return "", err
-	}
-
-	return string(output), nil
-}
-
 func createDockerClient() (*client.Client, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
-	defer cli.Close()
 
 	return cli, nil
 }
 
-func ExecuteCodeInDocker(language, code string) (string, error) {
-	// create a temporary dir to contain file and Dockerfile inside it
-	dir, err := os.MkdirTemp("", "code")
-	if err != nil {
-		return "", err
-	}
-	defer cleanUpTempDir(dir)
+// SplitHarnessOutput separates whatever the user code printed from the results line printed last by the question harness
+func SplitHarnessOutput(stdout string) (string, string) {
+	stdout = strings.TrimRight(stdout, "\n")
 
-	// get code file name based on language
-	fileName, err := getCodeFileName(language)
-	if err != nil {
-		return "", err
+	index := strings.LastIndex(stdout, "\n")
+	if index == -1 {
+		return "", stdout
 	}
 
-	// write code in this file
-	filePath := fmt.Sprintf("%s/%s", dir, fileName)
-	err = os.WriteFile(filePath, []byte(code), 0744)
-	if err != nil {
-		return "", err
-	}
-
-	// get dockerfile content based on language
-	dockerfileContent, err := getDockerfileContent(language)
-	if err != nil {
-		return "", err
-	}
-
-	// write dockerfile in this dir
-	dockerfilePath := fmt.Sprintf("%s/Dockerfile", dir)
-	err = os.WriteFile(dockerfilePath, []byte(dockerfileContent), 0744)
-	if err != nil {
-		return "", err
-	}
-
-	// create docker client
-	client, err := createDockerClient()
-	if err != nil {
-		return "", err
-	}
-
-	// build Image from dockerfile
-	dockerImageName := getImageName(language)
-	tags := []string{dockerImageName}
-	err = buildImageFromDockerfile(client, tags, dockerfilePath)
-	if err != nil {
-		return "", err
-	}
-
-	// run the container from image
-	containerName := strings.Replace(dockerImageName, "image", "container", 1)
-	containerOutput, err := runContainer(client, dir, dockerImageName, containerName)
-	if err != nil {
-		return "", err
-	}
+	return stdout[:index+1], stdout[index+1:]
+}
 
-	output := regexp.MustCompile(`�\[`).ReplaceAllString(containerOutput, "[")
-	output = regexp.MustCompile(`'`).ReplaceAllString(output, `"`)
+// NormalizeHarnessOutput turns the output printed by the question harness into valid JSON
+func NormalizeHarnessOutput(harnessOutput string) string {
+	output := regexp.MustCompile(`'`).ReplaceAllString(harnessOutput, `"`)
 	output = regexp.MustCompile(`\b(True|False)\b`).ReplaceAllStringFunc(output, func(match string) string {
 		if match == "True" {
 			return "true"
@@ -394,10 +251,5 @@ func ExecuteCodeInDocker(language, code string) (string, error) {
 	})
 	output = regexp.MustCompile(`"output": (\d+)`).ReplaceAllString(output, `"output": "$1"`)
 
-	err = removeDockerImage(client, dockerImageName)
-	if err != nil {
-		return "", err
-	}
-
-	return output, nil
-}
\ No newline at end of file
+	return output
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
@@ -9,6 +10,21 @@ import (
 )
 
 func SendEmail(email, username, verificationCode string) error {
+	return sendMail(email, "🔐 Your Verification Code for CodePulse", fmt.Sprintf("Hello %s! This is your verification code for CodePulse: <b>%s</b>", username, verificationCode))
+}
+
+// SendPasswordResetEmail sends the link to the page of the frontend where the password can be reset with the token
+func SendPasswordResetEmail(email, username, token string) error {
+	link := fmt.Sprintf("%s?token=%s", config.Config.PASSWORD_RESET_URL, url.QueryEscape(token))
+	minutes := int(config.Config.PASSWORD_RESET_TOKEN_TTL.Minutes())
+
+	return sendMail(email, "🔑 Reset your CodePulse password", fmt.Sprintf(
+		"Hello %s! Click <a href=\"%s\">here</a> to reset your CodePulse password. The link works once and expires in %d minutes. If you did not ask for it, you can ignore this email.",
+		username, link, minutes,
+	))
+}
+
+func sendMail(email, subject, body string) error {
 	var credentials = map[string]interface{}{
 		"from":     config.Config.FROM_EMAIL,
 		"username": config.Config.SMTP_USERNAME,
@@ -26,8 +42,8 @@ func SendEmail(email, username, verificationCode string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", credentials["from"].(string))
 	m.SetHeader("To", email)
-	m.SetHeader("Subject", "🔐 Your Verification Code for CodePulse")
-	m.SetBody("text/html", fmt.Sprintf("Hello %s! This is your verification code for CodePulse: <b>%s</b>", username, verificationCode))
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body)
 
 	port, err := strconv.Atoi(credentials["port"].(string))
 	if err != nil {
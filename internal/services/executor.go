@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+// ExecutionLimits are the resource limits applied to a single run
+type ExecutionLimits struct {
+	MemoryBytes   int64 `json:"memory"`
+	Processes     int64 `json:"processes"`
+	FileSizeBytes int64 `json:"fileSize"`
+	OpenFiles     int64 `json:"openFiles"`
+}
+
+// merge returns the limits with every limit set in other replaced
+func (l ExecutionLimits) merge(other ExecutionLimits) ExecutionLimits {
+	if other.MemoryBytes > 0 {
+		l.MemoryBytes = other.MemoryBytes
+	}
+	if other.Processes > 0 {
+		l.Processes = other.Processes
+	}
+	if other.FileSizeBytes > 0 {
+		l.FileSizeBytes = other.FileSizeBytes
+	}
+	if other.OpenFiles > 0 {
+		l.OpenFiles = other.OpenFiles
+	}
+	return l
+}
+
+type ExecutionRequest struct {
+	Language string
+	Code     string
+	Files    map[string]string // files of a multi-file project (path -> content), used instead of Code
+	Entry    string            // path of the file the project is run from
+	Stdin    string
+	Args     []string // command-line arguments of the program
+	Limits   ExecutionLimits
+	Timeout  time.Duration
+	// limits of the run step when judging, exceeding them is a TLE or MLE (0 for no limit)
+	TimeLimit   time.Duration
+	MemoryLimit int64 // bytes
+}
+
+type ExecutionResult struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	// stdout or stderr was cut off at the output limit
+	Truncated bool           `json:"truncated,omitempty"`
+	ExitCode  int            `json:"exit_code"`
+	WallTime  int64          `json:"wall_time"` // milliseconds
+	CPUTime   int64          `json:"cpu_time"`  // milliseconds
+	Memory    int64          `json:"memory"`    // peak resident memory in KiB
+	Verdict   models.Verdict `json:"verdict"`
+}
+
+// prepareJob returns the files of a request and the commands which compile and run them in the job directory.
+// Interpreted languages have no compile step and get a nil command.
+func prepareJob(req ExecutionRequest) (map[string][]byte, []string, []string, error) {
+	files := map[string][]byte{}
+
+	if len(req.Files) > 0 {
+		err := ValidateProject(req.Language, req.Files, req.Entry)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		compileCommand, runCommand, err := getProjectCommands(req.Language, req.Files, req.Entry)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for name, content := range req.Files {
+			files[name] = []byte(content)
+		}
+		return files, compileCommand, runCommand, nil
+	}
+
+	fileName, err := getCodeFileName(req.Language)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	compileCommand, err := getCompileCommand(req.Language)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	runCommand, err := getRunCommand(req.Language)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	files[fileName] = []byte(req.Code)
+	return files, compileCommand, runCommand, nil
+}
+
+// RunRequest is a single run of a prepared program
+type RunRequest struct {
+	Stdin string
+	Args  []string
+	// streams of an interactive run, used instead of Stdin and the captured stdout when set
+	StdinStream  io.Reader
+	StdoutStream io.Writer
+	// limits of the run when judging, exceeding them is a TLE or MLE (0 for no limit)
+	TimeLimit   time.Duration
+	MemoryLimit int64 // bytes
+}
+
+// ExecutionSession is code prepared (copied and compiled) in a sandbox, which can be run many times
+type ExecutionSession interface {
+	Run(ctx context.Context, run RunRequest) (*ExecutionResult, error)
+	Close()
+}
+
+// Executor runs code for a language inside some kind of sandbox
+type Executor interface {
+	Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error)
+	// Prepare copies and compiles the code of the request, the stdin and limits of the request are not used.
+	// A failed compilation is returned as the result instead of a session.
+	Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error)
+}
+
+// execute prepares the code of a request and runs it once, the timeout of the request covers both
+func execute(ctx context.Context, executor Executor, req ExecutionRequest) (*ExecutionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	session, result, err := executor.Prepare(ctx, req)
+	if err != nil || result != nil {
+		return result, err
+	}
+	defer session.Close()
+
+	return session.Run(ctx, RunRequest{
+		Stdin:       req.Stdin,
+		Args:        req.Args,
+		TimeLimit:   req.TimeLimit,
+		MemoryLimit: req.MemoryLimit,
+	})
+}
+
+var DefaultLimits = ExecutionLimits{
+	MemoryBytes:   512 * 1024 * 1024,
+	Processes:     64,
+	FileSizeBytes: 16 * 1024 * 1024,
+	OpenFiles:     64,
+}
+
+const DefaultTimeout = 2 * time.Minute
+
+// errExecutionTimedOut is returned by the sandboxes when a run is stopped because of its timeout
+var errExecutionTimedOut = errors.New("execution timed out")
+
+// messages printed by the runtimes when an allocation fails
+var outOfMemoryMarkers = []string{
+	"MemoryError",
+	"java.lang.OutOfMemoryError",
+	"std::bad_alloc",
+	"JavaScript heap out of memory",
+	"fatal error: runtime: out of memory",
+}
+
+// getVerdict classifies a finished run by its exit code and stderr.
+// Exit codes above 128 mean that the program was killed by signal (exit code - 128).
+func getVerdict(exitCode int, stderr string) models.Verdict {
+	if exitCode == 0 {
+		return models.OK
+	}
+
+	switch exitCode - 128 {
+	case int(syscall.SIGKILL):
+		// the only SIGKILL a finished run can get is from the OOM killer
+		return models.MemoryLimitExceeded
+	case int(syscall.SIGXCPU):
+		return models.TimeLimitExceeded
+	}
+
+	for _, marker := range outOfMemoryMarkers {
+		if strings.Contains(stderr, marker) {
+			return models.MemoryLimitExceeded
+		}
+	}
+
+	return models.RuntimeError
+}
+
+// CodeExecutor is the executor used by the handlers, selected by config.Config.EXECUTOR
+// and wrapped by a Scheduler which bounds the executions running at once
+var CodeExecutor Executor
+
+func InitializeExecutor() error {
+	var executor Executor
+
+	switch config.Config.EXECUTOR {
+	case constants.EXECUTOR_DOCKER:
+		// build the sandbox images and warm up the container pools
+		err := InitializeRunner()
+		if err != nil {
+			return err
+		}
+		executor = &DockerExecutor{runner: DockerRunner}
+	case constants.EXECUTOR_PROCESS:
+		processExecutor, err := NewProcessExecutor()
+		if err != nil {
+			return err
+		}
+		executor = processExecutor
+	default:
+		return fmt.Errorf("unsupported executor: %s", config.Config.EXECUTOR)
+	}
+
+	limits := SchedulerLimits{
+		MaxRunning:       config.Config.EXECUTION_MAX_RUNNING,
+		MaxPerLanguage:   config.Config.EXECUTION_MAX_PER_LANGUAGE,
+		MaxQueued:        config.Config.EXECUTION_MAX_QUEUED,
+		MaxQueuedPerUser: config.Config.EXECUTION_MAX_QUEUED_PER_USER,
+	}
+	if limits.MaxRunning <= 0 || limits.MaxPerLanguage <= 0 || limits.MaxQueued < 0 || limits.MaxQueuedPerUser < 0 {
+		return fmt.Errorf("invalid execution limits: %+v", limits)
+	}
+
+	CodeExecutor = NewScheduler(executor, limits)
+
+	return nil
+}
+
+// DockerExecutor runs code in the pooled sandbox containers
+type DockerExecutor struct {
+	runner *Runner
+}
+
+func (e *DockerExecutor) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return execute(ctx, e, req)
+}
+
+func (e *DockerExecutor) Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error) {
+	files, compileCommand, runCommand, err := prepareJob(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the timeout covers waiting for a free container as well as compiling
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	job, result, err := e.runner.Prepare(ctx, req.Language, req.Limits.MemoryBytes, files, compileCommand, runCommand, req.Timeout)
+	if err != nil || result != nil {
+		return nil, result, err
+	}
+
+	return &dockerSession{job: job}, nil, nil
+}
+
+type dockerSession struct {
+	job *RunnerJob
+}
+
+func (s *dockerSession) Run(ctx context.Context, run RunRequest) (*ExecutionResult, error) {
+	var runTimeout time.Duration
+	if run.TimeLimit > 0 {
+		runTimeout = getRunTimeout(run.TimeLimit)
+	}
+
+	stdin := run.StdinStream
+	if stdin == nil {
+		stdin = strings.NewReader(run.Stdin)
+	}
+
+	result, err := s.job.Run(ctx, stdin, run.StdoutStream, run.Args, runTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	applyJudgeLimits(run, result)
+	return result, nil
+}
+
+func (s *dockerSession) Close() {
+	s.job.Close()
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+func TestGetVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		stderr   string
+		want     models.Verdict
+	}{
+		{"success", 0, "", models.OK},
+		{"success with stderr", 0, "warning", models.OK},
+		{"exit code", 1, "Traceback (most recent call last):", models.RuntimeError},
+		{"killed by the OOM killer", 128 + 9, "", models.MemoryLimitExceeded},
+		{"cpu limit", 128 + 24, "", models.TimeLimitExceeded},
+		{"segmentation fault", 128 + 11, "", models.RuntimeError},
+		{"abort", 128 + 6, "terminate called after throwing an instance of 'std::logic_error'", models.RuntimeError},
+		{"python out of memory", 1, "MemoryError", models.MemoryLimitExceeded},
+		{"java out of memory", 1, "Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space", models.MemoryLimitExceeded},
+		{"c++ out of memory", 128 + 6, "terminate called after throwing an instance of 'std::bad_alloc'", models.MemoryLimitExceeded},
+		{"node out of memory", 134, "FATAL ERROR: Reached heap limit Allocation failed - JavaScript heap out of memory", models.MemoryLimitExceeded},
+		{"go out of memory", 2, "fatal error: runtime: out of memory", models.MemoryLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getVerdict(tt.exitCode, tt.stderr); got != tt.want {
+				t.Errorf("getVerdict(%d, %q) = %s, want %s", tt.exitCode, tt.stderr, got, tt.want)
+			}
+		})
+	}
+}
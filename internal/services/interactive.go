@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// interactorGracePeriod is the time the interactor gets on top of the run timeout of the user program,
+// so that it can still decide after the user program was stopped
+const interactorGracePeriod = time.Second
+
+// turnTimer stops an interaction when the user program does not answer the interactor within the timeout
+type turnTimer struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	timer    *time.Timer
+	finished bool
+	expired  bool
+	onExpire func()
+}
+
+// start begins a turn of the user program unless one is already running
+func (t *turnTimer) start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer == nil && !t.finished {
+		t.timer = time.AfterFunc(t.timeout, t.expire)
+	}
+}
+
+// stop ends the turn of the user program
+func (t *turnTimer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
+
+// finish stops the timer for good once the user program exited
+func (t *turnTimer) finish() {
+	t.mu.Lock()
+	t.finished = true
+	t.mu.Unlock()
+
+	t.stop()
+}
+
+func (t *turnTimer) expire() {
+	t.mu.Lock()
+	if t.finished {
+		t.mu.Unlock()
+		return
+	}
+	t.expired = true
+	t.mu.Unlock()
+
+	t.onExpire()
+}
+
+func (t *turnTimer) hasExpired() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.expired
+}
+
+// relay copies one side of an interaction to the other, calling onData for everything read.
+// Once the other side is gone the data is thrown away, so a program writing to it is never blocked or killed.
+// The destination is closed when the source ends, which is how a program gets EOF.
+func relay(src *os.File, dst *os.File, onData func()) {
+	defer dst.Close()
+
+	buffer := make([]byte, 32*1024)
+	failed := false
+
+	for {
+		n, err := src.Read(buffer)
+		if n > 0 {
+			onData()
+			if !failed {
+				_, writeErr := dst.Write(buffer[:n])
+				failed = writeErr != nil
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// runInteraction runs the prepared user program and interactor side by side, with the stdout of each one piped
+// into the stdin of the other. The interactor gets the test case input as its only argument.
+// It returns the results of both programs and whether the user program took longer than the turn timeout to answer.
+func runInteraction(ctx context.Context, session, interactor ExecutionSession, input string, run RunRequest, turnTimeout time.Duration) (*ExecutionResult, *ExecutionResult, bool, error) {
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	newPipe := func() (*os.File, *os.File, error) {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, reader, writer)
+		return reader, writer, nil
+	}
+
+	// the programs write into one pipe and read from another, the relays in between watch the turns
+	userStdinReader, userStdinWriter, err := newPipe()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	userStdoutReader, userStdoutWriter, err := newPipe()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	interactorStdinReader, interactorStdinWriter, err := newPipe()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	interactorStdoutReader, interactorStdoutWriter, err := newPipe()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, getRunTimeout(run.TimeLimit)+interactorGracePeriod)
+	defer cancel()
+
+	// both programs are stopped when a turn takes too long
+	turn := &turnTimer{timeout: turnTimeout, onExpire: cancel}
+	defer turn.finish()
+
+	// a turn of the user program starts when the interactor writes and ends when the user program answers
+	go relay(interactorStdoutReader, userStdinWriter, turn.start)
+	go relay(userStdoutReader, interactorStdinWriter, turn.stop)
+
+	var interactorResult *ExecutionResult
+	var interactorErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		interactorResult, interactorErr = interactor.Run(ctx, RunRequest{
+			Args:         []string{input},
+			StdinStream:  interactorStdinReader,
+			StdoutStream: interactorStdoutWriter,
+		})
+
+		// the user program gets EOF
+		interactorStdoutWriter.Close()
+		interactorStdinReader.Close()
+	}()
+
+	run.StdinStream = userStdinReader
+	run.StdoutStream = userStdoutWriter
+	userResult, userErr := session.Run(ctx, run)
+
+	// the interactor gets EOF
+	turn.finish()
+	userStdoutWriter.Close()
+	userStdinReader.Close()
+
+	wg.Wait()
+
+	if userErr != nil {
+		return nil, nil, false, userErr
+	}
+	if interactorErr != nil {
+		return nil, nil, false, interactorErr
+	}
+
+	return userResult, interactorResult, turn.hasExpired(), nil
+}
@@ -0,0 +1,513 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// retryableError is a failure of the database or the executor while judging, judging the submission again may succeed
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// IsRetryableJudgeError tells whether judging failed for a reason which is not the fault of the submission or its question
+func IsRetryableJudgeError(err error) bool {
+	var retryable *retryableError
+	return errors.As(err, &retryable)
+}
+
+// questionRun is the code of a user prepared for the test cases of a question,
+// every test case is run as its own process without compiling the code again
+type questionRun struct {
+	session     ExecutionSession
+	question    *models.Question
+	language    string
+	testCases   []models.TestCase
+	checker     *outputChecker
+	timeLimit   time.Duration
+	memoryLimit int64
+	// the interactor of an interactive question, prepared next to the user code
+	interactor  ExecutionSession
+	turnTimeout time.Duration
+}
+
+// prepareQuestionRun compiles the user code, or the harness calling it, to run the given test cases.
+// The program never holds a test case, every run gets only its own one. A failed compilation is
+// returned as the result instead of a run.
+func prepareQuestionRun(ctx context.Context, question *models.Question, language, code string, testCases []models.TestCase) (*questionRun, *ExecutionResult, error) {
+	program := code
+	checker := question.Checker
+
+	if question.Mode == models.InteractiveMode {
+		// the interactor decides, there is no output to check
+		if question.Interactor == nil {
+			return nil, nil, fmt.Errorf("interactive question %s has no interactor", question.ID)
+		}
+	} else if question.Mode == models.StdioMode {
+		// whitespace does not matter for program output unless the question says otherwise
+		if checker == nil {
+			checker = &models.Checker{Type: models.TokenChecker}
+		}
+	} else if question.Signature != nil {
+		// the harness is generated from the typed signature
+		var err error
+		program, err = utils.GenerateSignatureCodeTemplate(language, question.Signature, code)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		// older questions without a signature take the function name from the code snippet
+		var codeSnippet string
+		for _, snippet := range question.CodeSnippets {
+			if strings.ToLower(string(snippet.Language)) == language {
+				codeSnippet = strings.TrimSpace(snippet.Code)
+				break
+			}
+		}
+
+		// generate the code by replacing placeholders
+		program = utils.GenerateCodeTemplate(language, codeSnippet, code)
+	}
+
+	timeLimit, memoryLimit := GetQuestionLimits(question, language)
+
+	session, result, err := CodeExecutor.Prepare(ctx, ExecutionRequest{
+		Language: language,
+		Code:     program,
+		Limits:   getJudgeSandboxLimits(memoryLimit),
+		Timeout:  DefaultTimeout,
+	})
+	if err != nil {
+		return nil, nil, &retryableError{err}
+	}
+	if result != nil {
+		return nil, result, nil
+	}
+
+	run := &questionRun{
+		session:     session,
+		question:    question,
+		language:    language,
+		testCases:   testCases,
+		timeLimit:   timeLimit,
+		memoryLimit: memoryLimit,
+	}
+
+	if question.Mode == models.InteractiveMode {
+		err = run.prepareInteractor(ctx)
+	} else {
+		// the checker runs within the slot of the user code, like the interactor
+		run.checker, err = prepareOutputChecker(withinExecution(ctx), checker)
+	}
+	if err != nil {
+		session.Close()
+		return nil, nil, err
+	}
+
+	return run, nil, nil
+}
+
+// prepareInteractor compiles the interactor of the question, it runs within the slot of the user code.
+// The user program has to answer the interactor within the turn timeout, by default its time limit.
+func (r *questionRun) prepareInteractor(ctx context.Context) error {
+	interactor := r.question.Interactor
+
+	r.turnTimeout = r.timeLimit
+	if interactor.TurnTimeout > 0 {
+		r.turnTimeout = time.Duration(interactor.TurnTimeout) * time.Millisecond
+	}
+
+	session, result, err := CodeExecutor.Prepare(withinExecution(ctx), ExecutionRequest{
+		Language: interactor.Language,
+		Code:     interactor.Code,
+		Limits:   DefaultLimits,
+		Timeout:  DefaultTimeout,
+	})
+	if err != nil {
+		return &retryableError{fmt.Errorf("failed to prepare the interactor: %w", err)}
+	}
+	if result != nil {
+		return fmt.Errorf("interactor failed with %s: %s", result.Verdict, result.Stderr)
+	}
+
+	r.interactor = session
+	return nil
+}
+
+// runInteractiveTestCase runs the test case at index i against the interactor, which decides the verdict.
+// Running out of time or memory is on the user program even if the interactor rejected it because of that.
+func (r *questionRun) runInteractiveTestCase(ctx context.Context, i int) (*ExecutionResult, *models.TestCaseResult, error) {
+	testCase := r.testCases[i]
+
+	run := RunRequest{
+		TimeLimit:   r.timeLimit,
+		MemoryLimit: r.memoryLimit,
+	}
+
+	result, interactorResult, turnExpired, err := runInteraction(ctx, r.session, r.interactor, testCase.Input, run, r.turnTimeout)
+	if err != nil {
+		return nil, nil, &retryableError{err}
+	}
+
+	if turnExpired {
+		result.Verdict = models.TimeLimitExceeded
+		return result, nil, nil
+	}
+
+	switch result.Verdict {
+	case models.TimeLimitExceeded, models.MemoryLimitExceeded, models.OutputLimitExceeded:
+		return result, nil, nil
+	}
+
+	var accepted bool
+	switch interactorResult.Verdict {
+	case models.OK:
+		accepted = true
+	case models.RuntimeError:
+		// any other exit code rejects the interaction
+		accepted = false
+	default:
+		return nil, nil, fmt.Errorf("interactor failed with %s: %s", interactorResult.Verdict, interactorResult.Stderr)
+	}
+
+	// a user program failing after it was accepted still fails
+	if accepted && result.Verdict != models.OK {
+		return result, nil, nil
+	}
+
+	testCaseResult := &models.TestCaseResult{
+		Input:    testCase.Input,
+		Output:   strings.TrimSpace(interactorResult.Stderr),
+		Expected: testCase.Output,
+		Result:   accepted,
+		Verdict:  models.WrongAnswer,
+		Time:     result.CPUTime,
+		Memory:   result.Memory,
+	}
+	if accepted {
+		testCaseResult.Verdict = models.Accepted
+	}
+
+	return result, testCaseResult, nil
+}
+
+// runTestCase runs the test case at index i with the limits of the question.
+// The result of the test case is only available when the program finished normally.
+func (r *questionRun) runTestCase(ctx context.Context, i int) (*ExecutionResult, *models.TestCaseResult, error) {
+	if r.interactor != nil {
+		return r.runInteractiveTestCase(ctx, i)
+	}
+
+	testCase := r.testCases[i]
+
+	run := RunRequest{
+		TimeLimit:   r.timeLimit,
+		MemoryLimit: r.memoryLimit,
+	}
+	if r.question.Mode == models.StdioMode {
+		run.Stdin = testCase.Input
+	} else {
+		// the harness reads the test case from stdin
+		harnessInput, err := utils.GenerateHarnessInput(testCase, r.language, r.question.Signature)
+		if err != nil {
+			return nil, nil, err
+		}
+		run.Stdin = harnessInput
+	}
+
+	result, err := r.session.Run(ctx, run)
+	if err != nil {
+		return nil, nil, &retryableError{err}
+	}
+
+	if result.Verdict != models.OK {
+		return result, nil, nil
+	}
+
+	var testCaseResult models.TestCaseResult
+	if r.question.Mode == models.StdioMode {
+		testCaseResult = models.TestCaseResult{
+			Input:    testCase.Input,
+			Output:   result.Stdout,
+			Expected: testCase.Output,
+		}
+	} else {
+		// the harness prints the test case result as the last line of stdout
+		stdout, harnessOutput := SplitHarnessOutput(result.Stdout)
+
+		// the typed harnesses print valid JSON, the python one needs to be normalized
+		var results []models.TestCaseResult
+		err = json.Unmarshal([]byte(harnessOutput), &results)
+		if err != nil {
+			err = json.Unmarshal([]byte(NormalizeHarnessOutput(harnessOutput)), &results)
+		}
+		// the harness only misses its result when the user code broke it, e.g. by exiting early or printing a partial line
+		if err != nil || len(results) != 1 {
+			result.Verdict = models.RuntimeError
+			result.Stdout = stdout
+			result.Stderr = strings.TrimSpace(result.Stderr + "\nThe program did not return normally from the solution function")
+			return result, nil, nil
+		}
+
+		// only the output is taken from the harness, the user code can print anything, e.g. a result with its own expected output
+		result.Stdout = stdout
+		testCaseResult = models.TestCaseResult{
+			Input:    testCase.Input,
+			Output:   results[0].Output,
+			Expected: utils.HarnessExpectedOutput(testCase.Output, r.language, r.question.Signature),
+		}
+	}
+
+	// the checker of the question decides, the harness only prints the output.
+	// A custom checker runs within the slot of this run.
+	testCaseResult.Result, err = r.checker.Check(withinExecution(ctx), testCaseResult.Input, testCaseResult.Output, testCaseResult.Expected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	testCaseResult.Verdict = models.WrongAnswer
+	if testCaseResult.Result {
+		testCaseResult.Verdict = models.Accepted
+	}
+	testCaseResult.Time = result.CPUTime
+	testCaseResult.Memory = result.Memory
+
+	return result, &testCaseResult, nil
+}
+
+func (r *questionRun) Close() {
+	r.session.Close()
+	if r.interactor != nil {
+		r.interactor.Close()
+	}
+	if r.checker != nil {
+		r.checker.Close()
+	}
+}
+
+// RunQuestion runs the user code against the given test cases of a question.
+// The test case results are only available when every test case ran to completion,
+// otherwise the result of the first one which did not is returned.
+func RunQuestion(ctx context.Context, question *models.Question, language, code string, testCases []models.TestCase) (*ExecutionResult, []models.TestCaseResult, error) {
+	run, result, err := prepareQuestionRun(ctx, question, language, code, testCases)
+	if err != nil || result != nil {
+		return result, nil, err
+	}
+	defer run.Close()
+
+	total := &ExecutionResult{Verdict: models.OK}
+	results := []models.TestCaseResult{}
+
+	for i := range testCases {
+		result, testCaseResult, err := run.runTestCase(ctx, i)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if testCaseResult == nil {
+			return result, nil, nil
+		}
+		results = append(results, *testCaseResult)
+
+		// the output of a stdio program is the output of its test case
+		if question.Mode != models.StdioMode {
+			total.Stdout += result.Stdout
+		}
+		total.Stderr += result.Stderr
+		total.WallTime += result.WallTime
+		total.CPUTime = max(total.CPUTime, result.CPUTime)
+		total.Memory = max(total.Memory, result.Memory)
+	}
+
+	return total, results, nil
+}
+
+// JudgeSubmission runs a queued submission against the test cases of its question one by one and stores the outcome.
+// Progress is published as submission events while judging.
+func JudgeSubmission(submissionID string) error {
+	submission, err := models.GetSubmissionById(submissionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		logrus.Warnf("Submission %s not found", submissionID)
+		return nil
+	}
+	if err != nil {
+		return &retryableError{fmt.Errorf("failed to get submission %s: %w", submissionID, err)}
+	}
+
+	if submission.Status == models.Judged {
+		logrus.Warnf("Submission %s is already judged", submissionID)
+		return nil
+	}
+
+	question, err := models.GetQuestionById(submission.QuestionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("question %s not found", submission.QuestionID)
+	}
+	if err != nil {
+		return &retryableError{fmt.Errorf("failed to get question %s: %w", submission.QuestionID, err)}
+	}
+
+	err = models.UpdateSubmission(submissionID, bson.M{"status": models.Running})
+	if err != nil {
+		return &retryableError{err}
+	}
+
+	PublishSubmissionEvent(SubmissionEvent{
+		Type:         StatusEvent,
+		SubmissionID: submissionID,
+		Status:       models.Running,
+	})
+
+	compileCommand, err := getCompileCommand(submission.Language)
+	if err == nil && compileCommand != nil {
+		PublishSubmissionEvent(SubmissionEvent{
+			Type:         CompilingEvent,
+			SubmissionID: submissionID,
+		})
+	}
+
+	ctx := WithExecutionOwner(context.Background(), submission.UserID, true)
+
+	verdict := models.Accepted
+	stderr := ""
+	results := []models.TestCaseResult{}
+	passed := 0
+	total := len(question.TestCases)
+	var runtime, memory int64
+
+	// the code is compiled once for all test cases
+	run, compileResult, err := prepareQuestionRun(ctx, question, submission.Language, submission.Code, question.TestCases)
+	if err != nil {
+		return err
+	}
+
+	if compileResult != nil {
+		// a compile error is not the fault of a single test case, the diagnostics are kept as stderr
+		verdict = compileResult.Verdict
+		stderr = compileResult.Stderr
+	} else {
+		defer run.Close()
+
+		// like most judges the test cases are run in order until the first one fails
+		for i, testCase := range question.TestCases {
+			PublishSubmissionEvent(SubmissionEvent{
+				Type:         RunningEvent,
+				SubmissionID: submissionID,
+				TestCase:     i + 1,
+				Total:        total,
+			})
+
+			result, runResult, err := run.runTestCase(ctx, i)
+			if err != nil {
+				return err
+			}
+
+			testCaseResult := models.TestCaseResult{
+				Input:    testCase.Input,
+				Expected: testCase.Output,
+				Verdict:  result.Verdict,
+				Time:     result.CPUTime,
+				Memory:   result.Memory,
+			}
+			if runResult != nil {
+				testCaseResult = *runResult
+			}
+
+			if !question.IsSampleTestCase(i) {
+				testCaseResult = testCaseResult.Redacted()
+			}
+
+			runtime = max(runtime, result.CPUTime)
+			memory = max(memory, result.Memory)
+			results = append(results, testCaseResult)
+
+			PublishSubmissionEvent(SubmissionEvent{
+				Type:         TestCaseEvent,
+				SubmissionID: submissionID,
+				TestCase:     i + 1,
+				Total:        total,
+				Result:       &testCaseResult,
+				Verdict:      testCaseResult.Verdict,
+			})
+
+			if testCaseResult.Verdict != models.Accepted {
+				verdict = testCaseResult.Verdict
+				// the program can print the input of a hidden test case to stderr
+				if question.IsSampleTestCase(i) {
+					stderr = result.Stderr
+				}
+				break
+			}
+			passed++
+		}
+	}
+
+	err = models.UpdateSubmission(submissionID, bson.M{
+		"status":   models.Judged,
+		"verdict":  verdict,
+		"results":  results,
+		"passed":   passed,
+		"total":    total,
+		"runtime":  runtime,
+		"memory":   memory,
+		"stderr":   stderr,
+		"judgedAt": time.Now(),
+	})
+	if err != nil {
+		return &retryableError{err}
+	}
+
+	if verdict == models.Accepted {
+		err = models.AddAcceptedQuestion(submission.UserID, submission.QuestionID)
+		if err != nil {
+			logrus.Errorf("Error updating the user stats for submission %s: %v", submissionID, err)
+		}
+	}
+
+	PublishSubmissionEvent(SubmissionEvent{
+		Type:         VerdictEvent,
+		SubmissionID: submissionID,
+		Status:       models.Judged,
+		Verdict:      verdict,
+	})
+
+	return nil
+}
+
+// FailSubmission ends the judging of a submission which can not be judged, e.g. because its question was deleted.
+// The error is logged, the user only sees that something went wrong.
+func FailSubmission(submissionID string, err error) error {
+	logrus.Errorf("Error judging submission %s: %v", submissionID, err)
+
+	message := "Something went wrong while judging the submission"
+	PublishSubmissionEvent(SubmissionEvent{
+		Type:         VerdictEvent,
+		SubmissionID: submissionID,
+		Status:       models.Judged,
+		Error:        message,
+	})
+
+	return models.UpdateSubmission(submissionID, bson.M{
+		"status":   models.Judged,
+		"error":    message,
+		"judgedAt": time.Now(),
+	})
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+// the limits of a question are for compiled languages, slower runtimes get more
+var languageTimeMultipliers = map[string]float64{
+	"python":     3,
+	"javascript": 2,
+	"java":       2,
+}
+
+var languageMemoryMultipliers = map[string]float64{
+	"javascript": 1.5,
+	"java":       2,
+}
+
+// GetQuestionLimits returns the cpu time and memory (in bytes) a test case of the question may use in a language
+func GetQuestionLimits(question *models.Question, language string) (time.Duration, int64) {
+	timeLimit := question.TimeLimit
+	if timeLimit <= 0 {
+		timeLimit = constants.DEFAULT_TIME_LIMIT
+	}
+
+	memoryLimit := question.MemoryLimit
+	if memoryLimit <= 0 {
+		memoryLimit = constants.DEFAULT_MEMORY_LIMIT
+	}
+
+	timeMultiplier, ok := languageTimeMultipliers[language]
+	if !ok {
+		timeMultiplier = 1
+	}
+
+	memoryMultiplier, ok := languageMemoryMultipliers[language]
+	if !ok {
+		memoryMultiplier = 1
+	}
+
+	return time.Duration(float64(timeLimit)*timeMultiplier) * time.Millisecond,
+		int64(float64(memoryLimit)*memoryMultiplier) * 1024 * 1024
+}
+
+// getJudgeSandboxLimits returns the sandbox limits of a judged run, they must not stop
+// the program before it reaches the memory limit of the question
+func getJudgeSandboxLimits(memoryLimit int64) ExecutionLimits {
+	limits := DefaultLimits
+	limits.MemoryBytes = max(limits.MemoryBytes, memoryLimit)
+	return limits
+}
+
+// getRunTimeout is the wall time after which a run with a time limit is stopped,
+// waiting for input or sleeping does not use cpu time
+func getRunTimeout(timeLimit time.Duration) time.Duration {
+	return 2*timeLimit + time.Second
+}
+
+// applyJudgeLimits turns a run which used more cpu time or memory than the request allows into a TLE or MLE.
+// A program killed at the cpu rlimit gets SIGKILL like one killed by the OOM killer, its cpu time tells them apart.
+func applyJudgeLimits(req RunRequest, result *ExecutionResult) {
+	if result.Verdict != models.OK && result.Verdict != models.RuntimeError && result.Verdict != models.MemoryLimitExceeded {
+		return
+	}
+
+	if req.TimeLimit > 0 && result.CPUTime > req.TimeLimit.Milliseconds() {
+		result.Verdict = models.TimeLimitExceeded
+	} else if req.MemoryLimit > 0 && result.Memory*1024 > req.MemoryLimit {
+		result.Verdict = models.MemoryLimitExceeded
+	}
+}
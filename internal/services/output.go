@@ -0,0 +1,67 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+)
+
+// defaultOutputLimit is the maximum size of stdout and stderr of a run when it is not configured
+const defaultOutputLimit = 1024 * 1024
+
+// outputTruncatedMarker is appended to an output which was cut off at the limit
+const outputTruncatedMarker = "\n... output truncated"
+
+// errOutputLimitExceeded is returned by limitedBuffer once a program wrote more than the limit
+var errOutputLimitExceeded = errors.New("output limit exceeded")
+
+func getOutputLimit() int {
+	if config.Config != nil && config.Config.MAX_OUTPUT_SIZE > 0 {
+		return config.Config.MAX_OUTPUT_SIZE
+	}
+	return defaultOutputLimit
+}
+
+// limitedBuffer keeps at most limit bytes of a stream, writes after the limit fail with errOutputLimitExceeded.
+// onExceed is called once when the limit is reached, so the program can be stopped right away.
+type limitedBuffer struct {
+	buffer   bytes.Buffer
+	limit    int
+	exceeded bool
+	onExceed func()
+}
+
+func newLimitedBuffer(onExceed func()) *limitedBuffer {
+	return &limitedBuffer{
+		limit:    getOutputLimit(),
+		onExceed: onExceed,
+	}
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errOutputLimitExceeded
+	}
+
+	remaining := b.limit - b.buffer.Len()
+	if len(p) <= remaining {
+		return b.buffer.Write(p)
+	}
+
+	b.buffer.Write(p[:remaining])
+	b.exceeded = true
+	if b.onExceed != nil {
+		b.onExceed()
+	}
+
+	return remaining, errOutputLimitExceeded
+}
+
+// String returns the kept output, with a marker if it was truncated
+func (b *limitedBuffer) String() string {
+	if b.exceeded {
+		return b.buffer.String() + outputTruncatedMarker
+	}
+	return b.buffer.String()
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/database"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// tokens in a k-gram, renaming or reformatting code does not change the k-grams of its tokens
+	kgramSize = 8
+	// k-grams in a winnowing window, every match of at least kgramSize+winnowWindow-1 tokens is found
+	winnowWindow = 4
+	// submissions with fewer fingerprints are too short to tell copying from the obvious solution
+	minFingerprints = 8
+	// submissions checked by a single run of the detector
+	similarityBatchSize = 100
+)
+
+// keywords are kept as they are, every other identifier becomes the same token
+var languageKeywords = map[string][]string{
+	"python": {
+		"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif", "else", "except",
+		"False", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda", "None", "nonlocal", "not",
+		"or", "pass", "raise", "return", "True", "try", "while", "with", "yield",
+	},
+	"javascript": {
+		"async", "await", "break", "case", "catch", "class", "const", "continue", "default", "delete", "do", "else",
+		"false", "finally", "for", "function", "if", "in", "instanceof", "let", "new", "null", "of", "return", "switch",
+		"this", "throw", "true", "try", "typeof", "undefined", "var", "void", "while", "yield",
+	},
+	"go": {
+		"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go",
+		"goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type",
+		"var", "nil", "true", "false",
+	},
+	"cpp": {
+		"auto", "bool", "break", "case", "catch", "char", "class", "const", "continue", "default", "delete", "do",
+		"double", "else", "enum", "false", "float", "for", "if", "int", "long", "namespace", "new", "nullptr", "return",
+		"short", "signed", "sizeof", "static", "struct", "switch", "template", "this", "throw", "true", "try",
+		"typedef", "typename", "unsigned", "using", "void", "while",
+	},
+	"java": {
+		"abstract", "boolean", "break", "byte", "case", "catch", "char", "class", "continue", "default", "do",
+		"double", "else", "extends", "false", "final", "finally", "float", "for", "if", "implements", "import",
+		"instanceof", "int", "interface", "long", "new", "null", "private", "protected", "public", "return", "short",
+		"static", "super", "switch", "this", "throw", "throws", "true", "try", "void", "while",
+	},
+}
+
+// codeToken is a normalized token of a submission and the line it starts on
+type codeToken struct {
+	text string
+	line int
+}
+
+// tokenizeCode splits code into normalized tokens: comments and whitespace are dropped,
+// identifiers, strings and numbers are replaced by a placeholder each
+func tokenizeCode(language, code string) []codeToken {
+	keywords := languageKeywords[language]
+	hashComments := language == "python"
+	backtickStrings := language == "javascript" || language == "go"
+
+	runes := []rune(code)
+	tokens := []codeToken{}
+	line := 1
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := line
+
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case hashComments && r == '#', !hashComments && r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case !hashComments && r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += 2
+		case r == '"' || r == '\'' || backtickStrings && r == '`':
+			// python triple quoted strings end at the same three quotes
+			quote := string(r)
+			if hashComments && i+2 < len(runes) && runes[i+1] == r && runes[i+2] == r {
+				quote = strings.Repeat(quote, 3)
+			}
+			i += len(quote)
+			for i < len(runes) && !strings.HasPrefix(string(runes[i:min(i+len(quote), len(runes))]), quote) {
+				if runes[i] == '\\' && r != '`' {
+					i++
+				} else if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += len(quote)
+			tokens = append(tokens, codeToken{text: "STR", line: start})
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, codeToken{text: "NUM", line: start})
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '$') {
+				j++
+			}
+			word := string(runes[i:j])
+			i = j
+
+			if !slices.Contains(keywords, word) {
+				word = "ID"
+			}
+			tokens = append(tokens, codeToken{text: word, line: start})
+		default:
+			tokens = append(tokens, codeToken{text: string(r), line: start})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// hashKgrams returns the hash of every k-gram of the tokens, the k-gram at index i starts with token i
+func hashKgrams(tokens []codeToken) []int64 {
+	if len(tokens) < kgramSize {
+		return nil
+	}
+
+	hashes := make([]int64, 0, len(tokens)-kgramSize+1)
+	for i := 0; i+kgramSize <= len(tokens); i++ {
+		hash := fnv.New64a()
+		for _, token := range tokens[i : i+kgramSize] {
+			hash.Write([]byte(token.text))
+			hash.Write([]byte{0})
+		}
+		hashes = append(hashes, int64(hash.Sum64()))
+	}
+
+	return hashes
+}
+
+// winnow selects the smallest hash of every window of k-grams (the rightmost one on ties),
+// the set of selected hashes are the fingerprints of the code
+func winnow(hashes []int64) []int64 {
+	fingerprints := []int64{}
+	if len(hashes) == 0 {
+		return fingerprints
+	}
+
+	window := min(winnowWindow, len(hashes))
+	selected := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		minimum := start
+		for i := start; i < start+window; i++ {
+			if hashes[i] <= hashes[minimum] {
+				minimum = i
+			}
+		}
+
+		if minimum != selected {
+			selected = minimum
+			fingerprints = append(fingerprints, hashes[minimum])
+		}
+	}
+
+	slices.Sort(fingerprints)
+	return slices.Compact(fingerprints)
+}
+
+// fingerprintCode returns the sorted fingerprints of code, without the ones of the code snippet every user starts from
+func fingerprintCode(language, code string, base []int64) []int64 {
+	fingerprints := winnow(hashKgrams(tokenizeCode(language, code)))
+
+	return slices.DeleteFunc(fingerprints, func(fingerprint int64) bool {
+		_, found := slices.BinarySearch(base, fingerprint)
+		return found
+	})
+}
+
+// sharedFingerprints returns the fingerprints found in both sorted lists
+func sharedFingerprints(a, b []int64) map[int64]bool {
+	shared := map[int64]bool{}
+	for _, fingerprint := range a {
+		if _, found := slices.BinarySearch(b, fingerprint); found {
+			shared[fingerprint] = true
+		}
+	}
+	return shared
+}
+
+// matchRegions returns the lines of code covered by k-grams with one of the shared fingerprints, merged into regions
+func matchRegions(language, code string, shared map[int64]bool) []models.MatchRegion {
+	tokens := tokenizeCode(language, code)
+	regions := []models.MatchRegion{}
+
+	for i, hash := range hashKgrams(tokens) {
+		if !shared[hash] {
+			continue
+		}
+
+		startLine, endLine := tokens[i].line, tokens[i+kgramSize-1].line
+		if last := len(regions) - 1; last >= 0 && startLine <= regions[last].EndLine+1 {
+			regions[last].EndLine = max(regions[last].EndLine, endLine)
+			continue
+		}
+		regions = append(regions, models.MatchRegion{StartLine: startLine, EndLine: endLine})
+	}
+
+	return regions
+}
+
+// StartPlagiarismDetector compares new accepted submissions with the earlier ones of other users in the background,
+// every config.Config.PLAGIARISM_CHECK_INTERVAL
+func StartPlagiarismDetector() error {
+	interval, threshold := config.Config.PLAGIARISM_CHECK_INTERVAL, config.Config.PLAGIARISM_THRESHOLD
+	if interval <= 0 || threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("invalid plagiarism detection config: interval %s, threshold %v", interval, threshold)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// a backlog is checked batch by batch until it is gone
+			for {
+				checked, err := checkNewSubmissions()
+				if err != nil {
+					logrus.Errorf("Error checking submissions for plagiarism: %v", err)
+					break
+				}
+				if checked < similarityBatchSize {
+					break
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// checkNewSubmissions fingerprints a batch of the accepted submissions not checked yet, oldest first,
+// so that every pair of submissions is compared exactly once. It returns the number of submissions checked.
+func checkNewSubmissions() (int, error) {
+	collection := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.CODE_SUBMISSION_COLLECTION)
+
+	options := options.Find().SetSort(bson.M{"createdAt": 1}).SetLimit(similarityBatchSize)
+	cursor, err := collection.Find(context.TODO(), bson.M{
+		"status":             models.Judged,
+		"verdict":            models.Accepted,
+		"similarity_checked": bson.M{"$ne": true},
+	}, options)
+	if err != nil {
+		return 0, err
+	}
+
+	var submissions []models.QuestionSubmission
+	if err := cursor.All(context.TODO(), &submissions); err != nil {
+		return 0, err
+	}
+
+	// fingerprints of the code snippets of the questions, by question and language
+	snippetFingerprints := map[string][]int64{}
+
+	for _, submission := range submissions {
+		key := submission.QuestionID + "/" + submission.Language
+		base, ok := snippetFingerprints[key]
+		if !ok {
+			base = getSnippetFingerprints(submission.QuestionID, submission.Language)
+			snippetFingerprints[key] = base
+		}
+
+		err := checkSubmission(&submission, base)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(submissions), nil
+}
+
+func getSnippetFingerprints(questionID, language string) []int64 {
+	question, err := models.GetQuestionById(questionID)
+	if err != nil {
+		return nil
+	}
+
+	for _, snippet := range question.CodeSnippets {
+		if strings.ToLower(string(snippet.Language)) == language {
+			return fingerprintCode(language, snippet.Code, nil)
+		}
+	}
+
+	return nil
+}
+
+// checkSubmission compares a submission with the checked submissions of other users for the same question and language
+func checkSubmission(submission *models.QuestionSubmission, base []int64) error {
+	fingerprints := fingerprintCode(submission.Language, submission.Code, base)
+
+	if len(fingerprints) >= minFingerprints {
+		cursor, err := database.DBClient.Database(config.Config.DATABASE_NAME).Collection(constants.CODE_SUBMISSION_COLLECTION).Find(context.TODO(), bson.M{
+			"question_id":        submission.QuestionID,
+			"language":           submission.Language,
+			"user_id":            bson.M{"$ne": submission.UserID},
+			"similarity_checked": true,
+		})
+		if err != nil {
+			return err
+		}
+
+		var others []models.QuestionSubmission
+		if err := cursor.All(context.TODO(), &others); err != nil {
+			return err
+		}
+
+		for _, other := range others {
+			if len(other.Fingerprints) < minFingerprints {
+				continue
+			}
+
+			shared := sharedFingerprints(fingerprints, other.Fingerprints)
+			score := float64(len(shared)) / float64(min(len(fingerprints), len(other.Fingerprints)))
+			if score < config.Config.PLAGIARISM_THRESHOLD {
+				continue
+			}
+
+			err := models.SaveSimilarityPair(newSimilarityPair(submission, &other, shared, score))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return models.UpdateSubmission(submission.ID, bson.M{
+		"fingerprints":       fingerprints,
+		"similarity_checked": true,
+	})
+}
+
+func newSimilarityPair(a, b *models.QuestionSubmission, shared map[int64]bool, score float64) *models.SimilarityPair {
+	if b.UserID < a.UserID {
+		a, b = b, a
+	}
+
+	return &models.SimilarityPair{
+		QuestionID:  a.QuestionID,
+		Language:    a.Language,
+		UserA:       a.UserID,
+		SubmissionA: a.ID,
+		MatchesA:    matchRegions(a.Language, a.Code, shared),
+		UserB:       b.UserID,
+		SubmissionB: b.ID,
+		MatchesB:    matchRegions(b.Language, b.Code, shared),
+		Score:       score,
+		DetectedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func tokenTexts(tokens []codeToken) []string {
+	texts := []string{}
+	for _, token := range tokens {
+		texts = append(texts, token.text)
+	}
+	return texts
+}
+
+func TestTokenizeCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		want     []string
+	}{
+		{"identifiers and numbers", "python", "x = y + 10", []string{"ID", "=", "ID", "+", "NUM"}},
+		{"keywords are kept", "python", "return None", []string{"return", "None"}},
+		{"hash comment", "python", "x = 1 # y = 2", []string{"ID", "=", "NUM"}},
+		{"python strings", "python", `s = 'a#b' + "c"`, []string{"ID", "=", "STR", "+", "STR"}},
+		{"triple quoted string", "python", "s = '''a\n'b'\n'''", []string{"ID", "=", "STR"}},
+		{"escaped quote", "javascript", `s = "a\"b"`, []string{"ID", "=", "STR"}},
+		{"line comment", "go", "x := 1 // y", []string{"ID", ":", "=", "NUM"}},
+		{"block comment", "cpp", "int /* a\nb */ x;", []string{"int", "ID", ";"}},
+		{"backtick string", "go", "s := `a\\`", []string{"ID", ":", "=", "STR"}},
+		{"hash is an operator outside python", "cpp", "#include <x>", []string{"#", "ID", "<", "ID", ">"}},
+		{"decimal number", "java", "double d = 1.5e3;", []string{"double", "ID", "=", "NUM", ";"}},
+		{"dollar identifier", "javascript", "const $a = b_1", []string{"const", "ID", "=", "ID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenTexts(tokenizeCode(tt.language, tt.code)); !slices.Equal(got, tt.want) {
+				t.Errorf("tokenizeCode(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeCodeLines(t *testing.T) {
+	tokens := tokenizeCode("python", "a = 1\n\"\"\"x\ny\"\"\"\nb = 2")
+
+	lines := []int{}
+	for _, token := range tokens {
+		lines = append(lines, token.line)
+	}
+
+	want := []int{1, 1, 1, 2, 4, 4, 4}
+	if !slices.Equal(lines, want) {
+		t.Errorf("token lines = %v, want %v", lines, want)
+	}
+}
+
+func TestWinnow(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []int64
+		want   []int64
+	}{
+		{"no hashes", nil, []int64{}},
+		{"fewer hashes than a window", []int64{5, 3}, []int64{3}},
+		{"minimum of every window", []int64{77, 74, 42, 17, 98, 50, 17, 98, 8, 88, 67, 39, 77, 74, 42, 17, 98}, []int64{8, 17, 39}},
+		{"ties select the rightmost", []int64{1, 1, 1, 1, 1, 1}, []int64{1}},
+		{"increasing hashes", []int64{1, 2, 3, 4, 5, 6}, []int64{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := winnow(tt.hashes); !slices.Equal(got, tt.want) {
+				t.Errorf("winnow(%v) = %v, want %v", tt.hashes, got, tt.want)
+			}
+		})
+	}
+}
+
+const twoSumPython = `def two_sum(nums, target):
+    seen = {}
+    for i, num in enumerate(nums):
+        if target - num in seen:
+            return [seen[target - num], i]
+        seen[num] = i
+    return []
+`
+
+func TestFingerprintCode(t *testing.T) {
+	original := fingerprintCode("python", twoSumPython, nil)
+	if len(original) < minFingerprints {
+		t.Fatalf("got %d fingerprints, want at least %d", len(original), minFingerprints)
+	}
+
+	tests := []struct {
+		name      string
+		code      string
+		wantEqual bool
+	}{
+		{"renamed and reformatted", `def find(a, t):
+    # remember what was seen
+    d = {}
+    for j, x in enumerate(a):
+        if t - x in d:
+            return [d[t - x], j]
+        d[x] = j
+    return []
+`, true},
+		{"other algorithm", `def two_sum(nums, target):
+    for i in range(len(nums)):
+        for j in range(i + 1, len(nums)):
+            if nums[i] + nums[j] == target:
+                return [i, j]
+    return []
+`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprintCode("python", tt.code, nil)
+			if slices.Equal(got, original) != tt.wantEqual {
+				t.Errorf("fingerprints equal = %v, want %v", !tt.wantEqual, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestFingerprintCodeWithoutSnippet(t *testing.T) {
+	snippet := "def two_sum(nums, target):\n    seen = {}\n    for i, num in enumerate(nums):\n        pass\n"
+	base := fingerprintCode("python", snippet, nil)
+
+	fingerprints := fingerprintCode("python", twoSumPython, base)
+	for _, fingerprint := range fingerprints {
+		if _, found := slices.BinarySearch(base, fingerprint); found {
+			t.Fatalf("fingerprint %d of the snippet was kept", fingerprint)
+		}
+	}
+	if len(fingerprints) == 0 {
+		t.Error("every fingerprint was removed with the ones of the snippet")
+	}
+}
+
+func TestSharedFingerprints(t *testing.T) {
+	shared := sharedFingerprints([]int64{1, 3, 5, 7}, []int64{2, 3, 4, 7})
+	if len(shared) != 2 || !shared[3] || !shared[7] {
+		t.Errorf("sharedFingerprints() = %v, want 3 and 7", shared)
+	}
+}
+
+func TestMatchRegions(t *testing.T) {
+	copied := "x = 1\n" + twoSumPython
+	shared := sharedFingerprints(fingerprintCode("python", copied, nil), fingerprintCode("python", twoSumPython, nil))
+
+	regions := matchRegions("python", copied, shared)
+	if len(regions) != 1 {
+		t.Fatalf("matchRegions() = %v, want a single region", regions)
+	}
+
+	// the copied function is on lines 2 to 8, the extra line is not a match. Only the k-grams selected
+	// as fingerprints count, so the last tokens of the copy may not be covered.
+	if regions[0].StartLine != 2 || regions[0].EndLine < 6 || regions[0].EndLine > 8 {
+		t.Errorf("matchRegions() = %+v, want a region from line 2 to the end of the function", regions[0])
+	}
+}
@@ -0,0 +1,691 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+	"golang.org/x/sys/unix"
+)
+
+// ProcessExecutor runs code with the toolchains installed on the host.
+// Every run is a child process in fresh user, pid, mount, network, ipc and uts namespaces.
+// The child is this binary started with constants.SANDBOX_INIT_COMMAND, it pivots into a read-only root
+// holding only the toolchains and the job dir, applies rlimits and a seccomp filter to itself and then
+// execs the toolchain as an unprivileged user.
+type ProcessExecutor struct {
+	executable string
+	// the host user the sandbox user is mapped to
+	uid int
+	gid int
+	// host paths of the toolchains, mounted read-only in the sandbox
+	rootPaths []string
+}
+
+// sandboxUserID is the uid and gid of the code inside the sandbox
+const sandboxUserID = 1000
+
+// runtimes which reserve much more address space than they use get this much on top of the memory limit,
+// what they really use is still judged by their peak resident memory
+var addressSpaceReserve = map[string]int64{
+	"javascript": 4 * 1024 * 1024 * 1024,
+	"java":       4 * 1024 * 1024 * 1024,
+	"go":         1024 * 1024 * 1024,
+}
+
+func NewProcessExecutor() (*ProcessExecutor, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the server executable: %w", err)
+	}
+
+	// an unprivileged server can only map itself, root maps the sandbox to an unprivileged host user
+	uid, gid := os.Getuid(), os.Getgid()
+	if uid == 0 {
+		uid, gid = config.Config.SANDBOX_UID, config.Config.SANDBOX_GID
+		if uid <= 0 || gid <= 0 {
+			return nil, fmt.Errorf("the sandbox can't run as root, set SANDBOX_UID and SANDBOX_GID")
+		}
+	}
+
+	rootPaths := []string{}
+	for _, path := range strings.Split(config.Config.SANDBOX_ROOT_PATHS, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("sandbox root path %q is not absolute", path)
+		}
+		rootPaths = append(rootPaths, filepath.Clean(path))
+	}
+
+	return &ProcessExecutor{executable: executable, uid: uid, gid: gid, rootPaths: rootPaths}, nil
+}
+
+func (e *ProcessExecutor) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return execute(ctx, e, req)
+}
+
+func (e *ProcessExecutor) Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error) {
+	files, compileCommand, runCommand, err := prepareJob(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create a temporary dir to contain the code files
+	dir, err := os.MkdirTemp("", "code")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limits := req.Limits
+	if limits.MemoryBytes > 0 {
+		limits.MemoryBytes += addressSpaceReserve[req.Language]
+	}
+
+	session := &processSession{
+		executor:   e,
+		dir:        dir,
+		runCommand: runCommand,
+		limits:     limits,
+		timeout:    req.Timeout,
+	}
+
+	result, err := session.compile(ctx, files, compileCommand)
+	if err != nil || result != nil {
+		session.Close()
+		return nil, result, err
+	}
+
+	return session, nil, nil
+}
+
+type processSession struct {
+	executor   *ProcessExecutor
+	dir        string
+	runCommand []string
+	limits     ExecutionLimits // MemoryBytes is the address space limit
+	timeout    time.Duration
+}
+
+// compile returns a result only when the compilation failed
+func (s *processSession) compile(ctx context.Context, files map[string][]byte, compileCommand []string) (*ExecutionResult, error) {
+	// go ignores a go.mod in the temp dir, so it can not be the job dir
+	err := os.Mkdir(filepath.Join(s.dir, ".tmp"), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, content := range files {
+		filePath := filepath.Join(s.dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(filePath), 0755)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.WriteFile(filePath, content, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the job dir belongs to the sandbox user, which is a different host user when the server runs as root
+	if s.executor.uid != os.Getuid() {
+		err = filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, s.executor.uid, s.executor.gid)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if compileCommand == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// the diagnostics of the compiler are reported as the stderr of a compile error
+	result, err := s.executor.runSandboxed(ctx, s.dir, compileCommand, strings.NewReader(""), nil, nil, s.limits, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	if result.Verdict == models.TimeLimitExceeded {
+		return result, nil
+	}
+	if result.Verdict != models.OK {
+		result.Verdict = models.CompileError
+		return result, nil
+	}
+
+	return nil, nil
+}
+
+func (s *processSession) Run(ctx context.Context, run RunRequest) (*ExecutionResult, error) {
+	// a time limit caps the cpu time and the wall time of the run
+	timeout, cpuTimeout := s.timeout, s.timeout
+	if run.TimeLimit > 0 {
+		timeout, cpuTimeout = getRunTimeout(run.TimeLimit), run.TimeLimit
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdin := run.StdinStream
+	if stdin == nil {
+		stdin = strings.NewReader(run.Stdin)
+	}
+
+	result, err := s.executor.runSandboxed(ctx, s.dir, s.runCommand, stdin, run.StdoutStream, run.Args, s.limits, cpuTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	applyJudgeLimits(run, result)
+	return result, nil
+}
+
+func (s *processSession) Close() {
+	os.RemoveAll(s.dir)
+}
+
+// runSandboxed runs one command through the sandbox init process inside dir, stdout is captured unless a stream is given.
+// The streams of an interactive run should be pipes (*os.File), the child then uses them directly.
+func (e *ProcessExecutor) runSandboxed(ctx context.Context, dir string, command []string, stdin io.Reader, stdoutStream io.Writer, commandArgs []string, limits ExecutionLimits, timeout time.Duration) (*ExecutionResult, error) {
+	// the mount point of the sandbox root, it stays empty on the host
+	root, err := os.MkdirTemp("", "sandbox")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(root)
+
+	args := []string{
+		constants.SANDBOX_INIT_COMMAND,
+		"-root", root,
+		"-dir", dir,
+		"-paths", strings.Join(e.rootPaths, ","),
+		"-memory", fmt.Sprint(limits.MemoryBytes),
+		"-cpu", fmt.Sprint(int64(math.Ceil(timeout.Seconds())) + 1),
+		"-fsize", fmt.Sprint(limits.FileSizeBytes),
+		"-nproc", fmt.Sprint(limits.Processes),
+		"-nofile", fmt.Sprint(limits.OpenFiles),
+		"--",
+	}
+	args = append(args, command...)
+	args = append(args, commandArgs...)
+
+	// the program is killed as soon as it writes more than the output limit
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	stdout := newLimitedBuffer(stop)
+	stderr := newLimitedBuffer(stop)
+
+	cmd := exec.CommandContext(ctx, e.executable, args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + filepath.Join(dir, ".tmp"),
+		"GOCACHE=" + filepath.Join(dir, ".cache"),
+		"GOPATH=" + filepath.Join(dir, ".go"),
+		"GOTOOLCHAIN=local",
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	if stdoutStream != nil {
+		cmd.Stdout = stdoutStream
+	}
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: sandboxUserID, HostID: e.uid, Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: sandboxUserID, HostID: e.gid, Size: 1},
+		},
+		Credential: &syscall.Credential{Uid: sandboxUserID, Gid: sandboxUserID, NoSetGroups: true},
+		// only the sandbox init can mount, it clears the capability before the exec
+		AmbientCaps: []uintptr{unix.CAP_SYS_ADMIN},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+
+	startTime := time.Now()
+	err = cmd.Run()
+	wallTime := time.Since(startTime).Milliseconds()
+
+	if stdout.exceeded || stderr.exceeded {
+		return &ExecutionResult{
+			Stdout:    stdout.String(),
+			Stderr:    stderr.String(),
+			WallTime:  wallTime,
+			Truncated: true,
+			Verdict:   models.OutputLimitExceeded,
+		}, nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return &ExecutionResult{
+			WallTime: wallTime,
+			Verdict:  models.TimeLimitExceeded,
+		}, nil
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+
+	// report a kill by signal like a shell would, as 128 + signal
+	exitCode := cmd.ProcessState.ExitCode()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		exitCode = 128 + int(status.Signal())
+	}
+
+	result := &ExecutionResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: exitCode,
+		WallTime: wallTime,
+		Verdict:  getVerdict(exitCode, stderr.String()),
+	}
+
+	if usage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		result.CPUTime = (usage.Utime.Nano() + usage.Stime.Nano()) / int64(time.Millisecond)
+		result.Memory = usage.Maxrss
+	}
+
+	return result, nil
+}
+
+// syscalls which user code never needs, they fail with EPERM inside the sandbox
+var blockedSyscalls = []uint32{
+	unix.SYS_PTRACE,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_CHROOT,
+	unix.SYS_REBOOT,
+	unix.SYS_SWAPON,
+	unix.SYS_SWAPOFF,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_SETNS,
+	unix.SYS_UNSHARE,
+	unix.SYS_KEYCTL,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+}
+
+// clone flags creating namespaces, the sandbox relies on the user code staying in the namespaces it was started in
+const namespaceCloneFlags = unix.CLONE_NEWNS | unix.CLONE_NEWCGROUP | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+	unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET
+
+var auditArchitectures = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}
+
+func installSeccompFilter() error {
+	arch, ok := auditArchitectures[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	// offsets into struct seccomp_data, the flags of clone are the lower half of its first argument (little endian)
+	const nrOffset = 0
+	const archOffset = 4
+	const flagsOffset = 16
+
+	filter := []unix.SockFilter{
+		// kill the process if the syscall comes from a different architecture
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: archOffset},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, Jf: 0, K: arch},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_KILL_PROCESS},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: nrOffset},
+	}
+
+	for _, nr := range blockedSyscalls {
+		filter = append(filter,
+			unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: nr},
+			unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)},
+		)
+	}
+
+	filter = append(filter,
+		// the flags of clone3 are behind a pointer, ENOSYS makes libc fall back to clone
+		unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: unix.SYS_CLONE3},
+		unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS)},
+		// clone may start processes and threads, but not in new namespaces
+		unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 3, K: unix.SYS_CLONE},
+		unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: flagsOffset},
+		unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K, Jt: 0, Jf: 1, K: namespaceCloneFlags},
+		unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)},
+		unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+	)
+
+	program := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// required to install a filter without CAP_SYS_ADMIN, also stops setuid binaries from gaining privileges
+	err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	err = unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to install seccomp filter: %w", err)
+	}
+
+	return nil
+}
+
+// devices bound from the host into the sandbox
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom"}
+
+// bindMount mounts source on target and remounts it with the given flags.
+// Flags of the source mount are locked inside a user namespace, so they are kept.
+func bindMount(source, target string, flags uintptr) error {
+	err := unix.Mount(source, target, "", unix.MS_BIND, "")
+	if err != nil {
+		return fmt.Errorf("failed to bind %s: %w", source, err)
+	}
+
+	var stat unix.Statfs_t
+	err = unix.Statfs(source, &stat)
+	if err != nil {
+		return err
+	}
+
+	lockedFlags := map[int64]uintptr{
+		unix.ST_RDONLY: unix.MS_RDONLY,
+		unix.ST_NOSUID: unix.MS_NOSUID,
+		unix.ST_NODEV:  unix.MS_NODEV,
+		unix.ST_NOEXEC: unix.MS_NOEXEC,
+	}
+	for statFlag, mountFlag := range lockedFlags {
+		if stat.Flags&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+
+	err = unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|flags, "")
+	if err != nil {
+		return fmt.Errorf("failed to remount %s: %w", source, err)
+	}
+
+	return nil
+}
+
+// mountPoint creates an empty file or directory in the sandbox root to mount the host path on
+func mountPoint(root, path string, isDir bool) (string, error) {
+	target := filepath.Join(root, path)
+	if isDir {
+		return target, os.MkdirAll(target, 0755)
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return "", err
+	}
+	return target, os.WriteFile(target, nil, 0644)
+}
+
+// setupSandboxRoot builds the root of the sandbox in a tmpfs on root and pivots into it: the toolchain paths
+// read-only, the job dir read-write at its host path, a few devices and a /proc of the sandbox pid namespace.
+// Nothing else of the host is reachable afterwards.
+func setupSandboxRoot(root, dir string, paths []string) error {
+	// mounts inside the sandbox never propagate to the host
+	err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("failed to make the mounts private: %w", err)
+	}
+
+	err = unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=0755")
+	if err != nil {
+		return fmt.Errorf("failed to mount the root: %w", err)
+	}
+
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// merged /usr systems link /bin, /lib, ... into /usr
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			err = os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755)
+			if err != nil {
+				return err
+			}
+			err = os.Symlink(link, filepath.Join(root, path))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		target, err := mountPoint(root, path, info.IsDir())
+		if err != nil {
+			return err
+		}
+		err = bindMount(path, target, unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV)
+		if err != nil {
+			return err
+		}
+	}
+
+	target, err := mountPoint(root, dir, true)
+	if err != nil {
+		return err
+	}
+	err = bindMount(dir, target, unix.MS_NOSUID|unix.MS_NODEV)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range sandboxDevices {
+		target, err := mountPoint(root, device, false)
+		if err != nil {
+			return err
+		}
+		err = bindMount(device, target, unix.MS_NOSUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	links := map[string]string{
+		"/dev/fd":     "/proc/self/fd",
+		"/dev/stdin":  "/proc/self/fd/0",
+		"/dev/stdout": "/proc/self/fd/1",
+		"/dev/stderr": "/proc/self/fd/2",
+	}
+	for name, link := range links {
+		err = os.Symlink(link, filepath.Join(root, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	// a fresh /proc only shows the processes of the sandbox
+	target, err = mountPoint(root, "/proc", true)
+	if err != nil {
+		return err
+	}
+	err = unix.Mount("proc", target, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	if err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	oldRoot, err := mountPoint(root, "/.oldroot", true)
+	if err != nil {
+		return err
+	}
+	err = unix.PivotRoot(root, oldRoot)
+	if err != nil {
+		return fmt.Errorf("failed to pivot the root: %w", err)
+	}
+
+	err = unix.Chdir("/")
+	if err != nil {
+		return err
+	}
+	err = unix.Unmount("/.oldroot", unix.MNT_DETACH)
+	if err != nil {
+		return fmt.Errorf("failed to detach the host root: %w", err)
+	}
+	err = os.Remove("/.oldroot")
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, "")
+	if err != nil {
+		return fmt.Errorf("failed to make the root read-only: %w", err)
+	}
+
+	return unix.Chdir(dir)
+}
+
+func dropCapabilities() error {
+	err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	return unix.Capset(&header, &data[0])
+}
+
+func setResourceLimit(resource int, value int64) error {
+	if value <= 0 {
+		return nil
+	}
+
+	limit := uint64(value)
+	return unix.Setrlimit(resource, &unix.Rlimit{Cur: limit, Max: limit})
+}
+
+// RunSandboxInit is the entrypoint of the sandbox child process started by ProcessExecutor.
+// It never returns, the process is either replaced by the toolchain or exits with an error.
+func RunSandboxInit(args []string) {
+	// prctl and execve have to happen on the same thread for the filter to apply to the toolchain
+	runtime.LockOSThread()
+
+	flags := flag.NewFlagSet(constants.SANDBOX_INIT_COMMAND, flag.ContinueOnError)
+	root := flags.String("root", "", "empty dir the sandbox root is mounted on")
+	dir := flags.String("dir", "", "job dir")
+	paths := flags.String("paths", "", "comma separated host paths mounted read-only")
+	memory := flags.Int64("memory", 0, "max address space in bytes")
+	cpu := flags.Int64("cpu", 0, "max cpu time in seconds")
+	fsize := flags.Int64("fsize", 0, "max file size in bytes")
+	nproc := flags.Int64("nproc", 0, "max number of processes")
+	nofile := flags.Int64("nofile", 0, "max number of open files")
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() == 0 || *root == "" || *dir == "" {
+		fmt.Fprintf(os.Stderr, "sandbox: invalid arguments: %v\n", args)
+		os.Exit(127)
+	}
+
+	rootPaths := []string{}
+	if *paths != "" {
+		rootPaths = strings.Split(*paths, ",")
+	}
+
+	err = setupSandboxRoot(*root, *dir, rootPaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(127)
+	}
+
+	command := flags.Args()
+	binary, err := exec.LookPath(command[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(127)
+	}
+
+	// the toolchain runs without any capability, not even in the namespaces of the sandbox
+	err = dropCapabilities()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: failed to drop capabilities: %v\n", err)
+		os.Exit(127)
+	}
+
+	err = installSeccompFilter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(127)
+	}
+
+	// the limits come last, the go runtime of this process could fail to allocate or start a thread within them
+	environment := os.Environ()
+
+	// RLIMIT_DATA does not count mmap-ed memory of every runtime, the address space covers all of it
+	limits := map[int]int64{
+		unix.RLIMIT_AS:     *memory,
+		unix.RLIMIT_CPU:    *cpu,
+		unix.RLIMIT_FSIZE:  *fsize,
+		unix.RLIMIT_NPROC:  *nproc,
+		unix.RLIMIT_NOFILE: *nofile,
+	}
+	for resource, value := range limits {
+		err := setResourceLimit(resource, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: failed to set rlimit %d: %v\n", resource, err)
+			os.Exit(127)
+		}
+	}
+
+	// no core dumps
+	err = unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{Cur: 0, Max: 0})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: failed to disable core dumps: %v\n", err)
+		os.Exit(127)
+	}
+
+	err = unix.Exec(binary, command, environment)
+	fmt.Fprintf(os.Stderr, "sandbox: failed to exec %s: %v\n", binary, err)
+	os.Exit(127)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+// TestMain lets the process executor start the test binary as the sandbox child, like it starts the server binary
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == constants.SANDBOX_INIT_COMMAND {
+		RunSandboxInit(os.Args[2:])
+	}
+
+	os.Exit(m.Run())
+}
+
+// newTestProcessExecutor returns a process executor, the test is skipped where the sandbox can not run
+// (no user namespaces, or no python3 on the PATH within the sandbox root)
+func newTestProcessExecutor(t *testing.T) *ProcessExecutor {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("sandbox integration test")
+	}
+
+	previous := config.Config
+	config.Config = &config.Env{
+		SANDBOX_ROOT_PATHS: "/bin,/sbin,/lib,/lib32,/lib64,/usr,/etc,/opt",
+		SANDBOX_UID:        65534,
+		SANDBOX_GID:        65534,
+	}
+	t.Cleanup(func() {
+		config.Config = previous
+	})
+
+	executor, err := NewProcessExecutor()
+	if err != nil {
+		t.Skipf("process executor not available: %v", err)
+	}
+
+	// go test builds the binary in a dir only its user can read, the sandbox user has to be able to run it
+	executor.executable = copyTestExecutable(t, executor.executable)
+
+	result, err := executor.Execute(context.Background(), ExecutionRequest{
+		Language: "python",
+		Code:     "print('ok')",
+		Limits:   DefaultLimits,
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Skipf("sandbox not available: %v", err)
+	}
+	if result.Verdict != models.OK || strings.TrimSpace(result.Stdout) != "ok" {
+		t.Skipf("sandbox can not run python: %s %s", result.Verdict, result.Stderr)
+	}
+
+	return executor
+}
+
+func copyTestExecutable(t *testing.T, executable string) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "sandbox-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	err = os.Chmod(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := os.Open(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	copied := filepath.Join(dir, filepath.Base(executable))
+	target, err := os.OpenFile(copied, os.O_CREATE|os.O_WRONLY, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return copied
+}
+
+func TestProcessSandboxIsolation(t *testing.T) {
+	executor := newTestProcessExecutor(t)
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			"runs as the unprivileged sandbox user",
+			"import os\nprint(os.getuid(), os.getgid(), os.geteuid())",
+			"1000 1000 1000",
+		},
+		{
+			"has no capabilities",
+			"caps = [l.split()[1] for l in open('/proc/self/status') if l.startswith(('CapPrm', 'CapEff', 'CapAmb'))]\nprint(set(caps))",
+			"{'0000000000000000'}",
+		},
+		{
+			"root holds only the toolchains and the job dir",
+			"import os\nprint(os.path.exists('/root'), os.path.exists('/home'), os.path.exists('/var'), os.path.exists('/usr'))",
+			"False False False True",
+		},
+		{
+			"sees only its own processes",
+			"import os\nprint([p for p in os.listdir('/proc') if p.isdigit()])",
+			"['1']",
+		},
+		{
+			"root and toolchains are read-only",
+			"import os\nfor path in ['/x', '/usr/x', '/etc/x', '/tmp/x']:\n    try:\n        open(path, 'w')\n        print('writable', path)\n    except OSError:\n        pass\nprint('done')",
+			"done",
+		},
+		{
+			"job dir is writable",
+			"open('out.txt', 'w').write('data')\nprint(open('out.txt').read())",
+			"data",
+		},
+		{
+			"has no network",
+			"import socket\ntry:\n    socket.create_connection(('1.1.1.1', 53), timeout=1)\n    print('connected')\nexcept OSError:\n    print('blocked')",
+			"blocked",
+		},
+		{
+			"can not create namespaces",
+			"import ctypes, os, platform\nlibc = ctypes.CDLL(None, use_errno=True)\nCLONE_NEWUSER = 0x10000000\nblocked = [libc.unshare(CLONE_NEWUSER) == -1 and ctypes.get_errno() == 1]\npid = libc.syscall({'x86_64': 56, 'aarch64': 220}[platform.machine()], CLONE_NEWUSER | 17, 0, 0, 0, 0)\nif pid == 0:\n    os._exit(0)\nblocked.append(pid == -1 and ctypes.get_errno() == 1)\nprint(blocked)",
+			"[True, True]",
+		},
+		{
+			"can start threads and processes",
+			"import os, threading\nt = threading.Thread(target=lambda: print('thread'))\nt.start()\nt.join()\npid = os.fork()\nif pid == 0:\n    os._exit(3)\nprint(os.waitpid(pid, 0)[1] >> 8)",
+			"thread\n3",
+		},
+		{
+			"devices work",
+			"print(len(open('/dev/urandom', 'rb').read(8)), open('/dev/null').read() == '')",
+			"8 True",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ExecutionRequest{
+				Language: "python",
+				Code:     tt.code,
+				Limits:   DefaultLimits,
+				Timeout:  30 * time.Second,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Verdict != models.OK {
+				t.Fatalf("Execute() verdict = %s, stderr: %s", result.Verdict, result.Stderr)
+			}
+			if got := strings.TrimSpace(result.Stdout); got != tt.want {
+				t.Errorf("stdout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessSandboxLimits(t *testing.T) {
+	executor := newTestProcessExecutor(t)
+
+	tests := []struct {
+		name        string
+		code        string
+		limits      ExecutionLimits
+		timeLimit   time.Duration
+		memoryLimit int64
+		want        models.Verdict
+	}{
+		{
+			"memory over the limit",
+			"data = bytearray(512 * 1024 * 1024)\nprint(len(data))",
+			ExecutionLimits{MemoryBytes: 128 * 1024 * 1024, Processes: 64},
+			time.Second, 128 * 1024 * 1024,
+			models.MemoryLimitExceeded,
+		},
+		{
+			"memory within the limit",
+			"data = bytearray(16 * 1024 * 1024)\nprint(len(data))",
+			ExecutionLimits{MemoryBytes: 128 * 1024 * 1024, Processes: 64},
+			time.Second, 128 * 1024 * 1024,
+			models.OK,
+		},
+		{
+			"cpu time over the limit",
+			"while True:\n    pass",
+			DefaultLimits,
+			500 * time.Millisecond, 0,
+			models.TimeLimitExceeded,
+		},
+		{
+			"too many processes",
+			"import os\nfor _ in range(100):\n    if os.fork() == 0:\n        import time\n        time.sleep(5)\n        os._exit(0)",
+			ExecutionLimits{MemoryBytes: DefaultLimits.MemoryBytes, Processes: 8},
+			time.Second, 0,
+			models.RuntimeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ExecutionRequest{
+				Language:    "python",
+				Code:        tt.code,
+				Limits:      tt.limits,
+				Timeout:     30 * time.Second,
+				TimeLimit:   tt.timeLimit,
+				MemoryLimit: tt.memoryLimit,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Verdict != tt.want {
+				t.Errorf("Execute() verdict = %s, want %s, stderr: %s", result.Verdict, tt.want, result.Stderr)
+			}
+		})
+	}
+}
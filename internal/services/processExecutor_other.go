@@ -0,0 +1,30 @@
+//go:build !linux
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// ProcessExecutor needs linux namespaces, on other platforms only the docker executor is available
+type ProcessExecutor struct{}
+
+func NewProcessExecutor() (*ProcessExecutor, error) {
+	return nil, fmt.Errorf("process executor is not supported on %s", runtime.GOOS)
+}
+
+func (e *ProcessExecutor) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return nil, fmt.Errorf("process executor is not supported on %s", runtime.GOOS)
+}
+
+func (e *ProcessExecutor) Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error) {
+	return nil, nil, fmt.Errorf("process executor is not supported on %s", runtime.GOOS)
+}
+
+func RunSandboxInit(args []string) {
+	fmt.Fprintf(os.Stderr, "sandbox: not supported on %s\n", runtime.GOOS)
+	os.Exit(127)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+// projectBinary is where compiled projects are written, paths starting with a dot are reserved for the sandbox
+const (
+	projectBinary  = ".main"
+	projectClasses = ".classes"
+)
+
+// file extension of the entry point of a project per language
+var projectEntryExtensions = map[string]string{
+	"python":     ".py",
+	"javascript": ".js",
+	"go":         ".go",
+	"cpp":        ".cpp",
+	"java":       ".java",
+}
+
+var javaPackagePattern = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+
+// ValidateProject checks the files of a multi-file project (path -> content) and its entry point.
+// Paths are relative, slash separated and may not leave the project directory.
+func ValidateProject(language string, files map[string]string, entry string) error {
+	extension, ok := projectEntryExtensions[language]
+	if !ok {
+		return fmt.Errorf("unsupported language: %s", language)
+	}
+
+	if len(files) > constants.MAX_PROJECT_FILES {
+		return fmt.Errorf("a project can have at most %d files", constants.MAX_PROJECT_FILES)
+	}
+
+	size := 0
+	for name, content := range files {
+		if err := validateProjectPath(name); err != nil {
+			return err
+		}
+
+		if len(content) > constants.MAX_PROJECT_FILE_SIZE {
+			return fmt.Errorf("file %s is larger than %d bytes", name, constants.MAX_PROJECT_FILE_SIZE)
+		}
+		size += len(content)
+	}
+
+	if size > constants.MAX_PROJECT_SIZE {
+		return fmt.Errorf("a project can be at most %d bytes", constants.MAX_PROJECT_SIZE)
+	}
+
+	// a file can not also be a directory of another file
+	for name := range files {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := files[dir]; ok {
+				return fmt.Errorf("file %s is also a directory", dir)
+			}
+		}
+	}
+
+	if _, ok := files[entry]; !ok {
+		return fmt.Errorf("entry point %s is not a file of the project", entry)
+	}
+
+	if path.Ext(entry) != extension {
+		return fmt.Errorf("entry point of a %s project has to be a %s file", language, extension)
+	}
+
+	return nil
+}
+
+func validateProjectPath(name string) error {
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("invalid file path: %q", name)
+	}
+
+	if strings.HasPrefix(name, "/") || strings.Contains(name, `\`) || path.Clean(name) != name {
+		return fmt.Errorf("invalid file path: %s", name)
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		// also rejects "..", and hidden files which could clash with the files of the sandbox
+		if strings.HasPrefix(part, ".") {
+			return fmt.Errorf("invalid file path: %s", name)
+		}
+		for _, r := range part {
+			if r < 0x20 || r == 0x7f {
+				return fmt.Errorf("invalid file path: %q", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getProjectCommands returns the commands which compile and run a project from its entry point
+func getProjectCommands(language string, files map[string]string, entry string) ([]string, []string, error) {
+	switch language {
+	case "python":
+		return nil, []string{"python3", entry}, nil
+	case "javascript":
+		return nil, []string{"node", entry}, nil
+	case "go":
+		// a module is built as a package, otherwise the files next to the entry point are
+		if _, ok := files["go.mod"]; ok {
+			return []string{"go", "build", "-o", projectBinary, "./" + path.Dir(entry)}, []string{"./" + projectBinary}, nil
+		}
+		command := []string{"go", "build", "-o", projectBinary}
+		for _, name := range projectFiles(files, ".go") {
+			if path.Dir(name) == path.Dir(entry) && !strings.HasSuffix(name, "_test.go") {
+				command = append(command, name)
+			}
+		}
+		return command, []string{"./" + projectBinary}, nil
+	case "cpp":
+		command := append([]string{"g++", "-O2", "-I", ".", "-o", projectBinary}, projectFiles(files, ".cpp")...)
+		return command, []string{"./" + projectBinary}, nil
+	case "java":
+		// the main class is named after the entry point and its package
+		className := strings.TrimSuffix(path.Base(entry), ".java")
+		if matches := javaPackagePattern.FindStringSubmatch(files[entry]); matches != nil {
+			className = matches[1] + "." + className
+		}
+		command := append([]string{"javac", "-d", projectClasses}, projectFiles(files, ".java")...)
+		return command, []string{"java", "-cp", projectClasses, className}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported language: %s", language)
+}
+
+// projectFiles returns the sorted paths of the files with the given extension
+func projectFiles(files map[string]string, extension string) []string {
+	names := []string{}
+	for name := range files {
+		if path.Ext(name) == extension {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+func TestValidateProject(t *testing.T) {
+	tooManyFiles := map[string]string{}
+	for i := 0; i <= constants.MAX_PROJECT_FILES; i++ {
+		tooManyFiles[fmt.Sprintf("f%d.py", i)] = ""
+	}
+
+	tooLarge := map[string]string{"main.py": ""}
+	for i := 0; i < 5; i++ {
+		tooLarge[fmt.Sprintf("f%d.py", i)] = strings.Repeat("a", constants.MAX_PROJECT_FILE_SIZE)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		files    map[string]string
+		entry    string
+		wantErr  bool
+	}{
+		{"single file", "python", map[string]string{"main.py": "print(1)"}, "main.py", false},
+		{"nested files", "go", map[string]string{"go.mod": "module a", "cmd/main.go": "", "pkg/util.go": ""}, "cmd/main.go", false},
+		{"unsupported language", "ruby", map[string]string{"main.rb": ""}, "main.rb", true},
+		{"missing entry point", "python", map[string]string{"main.py": ""}, "app.py", true},
+		{"entry point of another language", "java", map[string]string{"Main.py": ""}, "Main.py", true},
+		{"absolute path", "python", map[string]string{"/etc/main.py": ""}, "/etc/main.py", true},
+		{"parent directory", "python", map[string]string{"../main.py": ""}, "../main.py", true},
+		{"unclean path", "python", map[string]string{"a//main.py": ""}, "a//main.py", true},
+		{"backslash", "python", map[string]string{`a\main.py`: ""}, `a\main.py`, true},
+		{"hidden file", "python", map[string]string{"main.py": "", ".main": ""}, "main.py", true},
+		{"hidden directory", "python", map[string]string{"main.py": "", "a/.b/c.py": ""}, "main.py", true},
+		{"control character", "python", map[string]string{"main.py": "", "a\nb.py": ""}, "main.py", true},
+		{"empty path", "python", map[string]string{"main.py": "", "": ""}, "main.py", true},
+		{"file is also a directory", "python", map[string]string{"main.py": "", "a": "", "a/b.py": ""}, "main.py", true},
+		{"too large file", "python", map[string]string{"main.py": strings.Repeat("a", constants.MAX_PROJECT_FILE_SIZE+1)}, "main.py", true},
+		{"too many files", "python", tooManyFiles, "f0.py", true},
+		{"too large project", "python", tooLarge, "main.py", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProject(tt.language, tt.files, tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetProjectCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		language    string
+		files       map[string]string
+		entry       string
+		wantCompile []string
+		wantRun     []string
+	}{
+		{
+			"python", "python", map[string]string{"app/main.py": ""}, "app/main.py",
+			nil, []string{"python3", "app/main.py"},
+		},
+		{
+			"go module", "go", map[string]string{"go.mod": "", "cmd/main.go": ""}, "cmd/main.go",
+			[]string{"go", "build", "-o", projectBinary, "./cmd"}, []string{"./" + projectBinary},
+		},
+		{
+			"go files next to the entry point", "go", map[string]string{"main.go": "", "util.go": "", "util_test.go": "", "other/x.go": ""}, "main.go",
+			[]string{"go", "build", "-o", projectBinary, "main.go", "util.go"}, []string{"./" + projectBinary},
+		},
+		{
+			"cpp", "cpp", map[string]string{"main.cpp": "", "lib/b.cpp": "", "lib/b.h": ""}, "main.cpp",
+			[]string{"g++", "-O2", "-I", ".", "-o", projectBinary, "lib/b.cpp", "main.cpp"}, []string{"./" + projectBinary},
+		},
+		{
+			"java package", "java", map[string]string{"app/Main.java": "package app;\nclass Main {}", "app/Util.java": ""}, "app/Main.java",
+			[]string{"javac", "-d", projectClasses, "app/Main.java", "app/Util.java"}, []string{"java", "-cp", projectClasses, "app.Main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compile, run, err := getProjectCommands(tt.language, tt.files, tt.entry)
+			if err != nil {
+				t.Fatalf("getProjectCommands() error = %v", err)
+			}
+			if !slices.Equal(compile, tt.wantCompile) {
+				t.Errorf("compile command = %q, want %q", compile, tt.wantCompile)
+			}
+			if !slices.Equal(run, tt.wantRun) {
+				t.Errorf("run command = %q, want %q", run, tt.wantRun)
+			}
+		})
+	}
+}
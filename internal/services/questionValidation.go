@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+// ValidateQuestion runs the reference solution of a question against all of its test cases.
+// Unlike judging it does not stop at the first failing test case, the report lists all of them.
+func ValidateQuestion(ctx context.Context, question *models.Question) (*models.ValidationReport, error) {
+	solution := question.ReferenceSolution
+
+	report := &models.ValidationReport{
+		Verdict:     models.Accepted,
+		Total:       len(question.TestCases),
+		Failures:    []models.TestCaseResult{},
+		ValidatedAt: time.Now(),
+	}
+
+	run, compileResult, err := prepareQuestionRun(ctx, question, solution.Language, solution.Code, question.TestCases)
+	if err != nil {
+		return nil, err
+	}
+
+	if compileResult != nil {
+		report.Verdict = compileResult.Verdict
+		report.Stderr = compileResult.Stderr
+		return report, nil
+	}
+	defer func() {
+		if run != nil {
+			run.Close()
+		}
+	}()
+
+	for i, testCase := range question.TestCases {
+		result, testCaseResult, err := run.runTestCase(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+
+		if testCaseResult != nil {
+			if testCaseResult.Verdict == models.Accepted {
+				report.Passed++
+				continue
+			}
+		} else {
+			testCaseResult = &models.TestCaseResult{
+				Input:    testCase.Input,
+				Expected: testCase.Output,
+				Verdict:  result.Verdict,
+				Time:     result.CPUTime,
+				Memory:   result.Memory,
+			}
+		}
+
+		// the verdict of the report is the one of the first failing test case
+		if report.Verdict == models.Accepted {
+			report.Verdict = testCaseResult.Verdict
+			report.Stderr = result.Stderr
+		}
+		report.Failures = append(report.Failures, *testCaseResult)
+
+		// a sandbox in which a run was stopped can not run again, the code is prepared again for the rest
+		if result.Verdict == models.TimeLimitExceeded || result.Verdict == models.OutputLimitExceeded {
+			run.Close()
+			run, compileResult, err = prepareQuestionRun(ctx, question, solution.Language, solution.Code, question.TestCases)
+			if err != nil {
+				return nil, err
+			}
+			if compileResult != nil {
+				return nil, fmt.Errorf("failed to prepare the reference solution again: %s", compileResult.Verdict)
+			}
+		}
+	}
+
+	report.Valid = report.Passed == report.Total
+	return report, nil
+}
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// label put on every sandbox container so that stale ones can be found and removed
+const sandboxContainerLabel = "codepulse.sandbox.language"
+
+// directory inside the sandbox container under which every job gets its own folder
+const sandboxWorkDir = "/sandbox"
+
+// user code runs as nobody, never as the root user of the container
+const sandboxUser = "65534:65534"
+
+// the root filesystem is read-only, only these tmpfs mounts are writable
+var sandboxTmpfs = map[string]string{
+	sandboxWorkDir: "rw,exec,nosuid,nodev,size=64m,mode=0755,uid=65534,gid=65534",
+	"/tmp":         "rw,nosuid,nodev,size=64m,mode=1777",
+}
+
+// environment of the sandbox containers, the home directory of nobody does not exist
+var sandboxEnv = []string{
+	"HOME=/tmp",
+	"GOCACHE=/tmp/go-build",
+	"GOTOOLCHAIN=local",
+}
+
+// a failed replacement of a pooled container is retried after replaceRetryDelay, doubling up to maxReplaceRetryDelay
+const (
+	replaceRetryDelay    = time.Second
+	maxReplaceRetryDelay = time.Minute
+)
+
+// sandboxCPUQuota is the cpu time a container gets every 100ms period (half a cpu)
+const sandboxCPUQuota = 50000
+
+var DockerRunner *Runner
+
+type sandboxContainer struct {
+	ID          string
+	Name        string
+	Language    string
+	MemoryBytes int64 // the current memory limit, jobs change it to the limit they need
+}
+
+type languagePool struct {
+	language   string
+	imageName  string
+	containers chan *sandboxContainer
+}
+
+// Runner keeps a pool of warm containers for every language.
+// Images are built once at startup and every job borrows a container from the pool.
+type Runner struct {
+	client         *client.Client
+	poolSize       int
+	pools          map[string]*languagePool
+	limits         map[string]ExecutionLimits
+	seccompProfile string
+}
+
+func InitializeRunner() error {
+	cli, err := createDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	poolSize := config.Config.RUNNER_POOL_SIZE
+	if poolSize <= 0 {
+		return fmt.Errorf("invalid runner pool size: %d", poolSize)
+	}
+
+	limits, err := getSandboxLimits()
+	if err != nil {
+		return err
+	}
+
+	// docker applies its default seccomp profile unless the containers get another one
+	var seccompProfile string
+	if config.Config.SANDBOX_SECCOMP_PROFILE != "" {
+		profile, err := os.ReadFile(config.Config.SANDBOX_SECCOMP_PROFILE)
+		if err != nil {
+			return fmt.Errorf("failed to read the seccomp profile: %w", err)
+		}
+		seccompProfile = string(profile)
+	}
+
+	runner := &Runner{
+		client:         cli,
+		poolSize:       poolSize,
+		pools:          make(map[string]*languagePool),
+		limits:         limits,
+		seccompProfile: seccompProfile,
+	}
+
+	// remove containers left behind by a previous run of the server
+	err = runner.removeStaleContainers()
+	if err != nil {
+		return err
+	}
+
+	for _, language := range supportedLanguages {
+		dockerfileContent, err := getDockerfileContent(language)
+		if err != nil {
+			return err
+		}
+
+		// build the toolchain image once
+		imageName := getImageName(language)
+		err = buildImageFromDockerfile(cli, []string{imageName}, dockerfileContent)
+		if err != nil {
+			return fmt.Errorf("failed to build image for %s: %w", language, err)
+		}
+
+		pool := &languagePool{
+			language:   language,
+			imageName:  imageName,
+			containers: make(chan *sandboxContainer, poolSize),
+		}
+
+		// warm up the pool
+		for i := 0; i < poolSize; i++ {
+			cont, err := runner.createContainer(pool)
+			if err != nil {
+				return fmt.Errorf("failed to create %s container: %w", language, err)
+			}
+			pool.containers <- cont
+		}
+
+		runner.pools[language] = pool
+		logrus.Infof("Sandbox pool ready for %s with %d containers", language, poolSize)
+	}
+
+	DockerRunner = runner
+
+	return nil
+}
+
+// getSandboxLimits returns the container limits of every language: DefaultLimits
+// overridden by the defaults of the language and then by config.Config.SANDBOX_LIMITS
+func getSandboxLimits() (map[string]ExecutionLimits, error) {
+	configured := map[string]ExecutionLimits{}
+	if config.Config.SANDBOX_LIMITS != "" {
+		err := json.Unmarshal([]byte(config.Config.SANDBOX_LIMITS), &configured)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox limits: %w", err)
+		}
+	}
+
+	limits := map[string]ExecutionLimits{}
+	for _, language := range supportedLanguages {
+		limits[language] = DefaultLimits.merge(languageLimits[language]).merge(configured[language])
+	}
+
+	return limits, nil
+}
+
+func (r *Runner) removeStaleContainers() error {
+	containers, err := r.client.ContainerList(context.Background(), container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", sandboxContainerLabel),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list sandbox containers: %w", err)
+	}
+
+	for _, cont := range containers {
+		err := r.client.ContainerRemove(context.Background(), cont.ID, container.RemoveOptions{
+			Force: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete container %s: %w", cont.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) createContainer(pool *languagePool) (*sandboxContainer, error) {
+	ctx := context.Background()
+
+	// every container gets a unique name so that runs of the same language never collide
+	containerName := fmt.Sprintf("%s-%s", strings.Replace(pool.imageName, "image", "container", 1), uuid.NewString()[:8])
+
+	limits := r.limits[pool.language]
+
+	securityOpt := []string{"no-new-privileges"}
+	if r.seccompProfile != "" {
+		securityOpt = append(securityOpt, "seccomp="+r.seccompProfile)
+	}
+
+	cont, err := r.client.ContainerCreate(
+		ctx,
+		&container.Config{
+			Image:           pool.imageName,
+			User:            sandboxUser,
+			Env:             sandboxEnv,
+			WorkingDir:      sandboxWorkDir,
+			NetworkDisabled: true,
+			Labels: map[string]string{
+				sandboxContainerLabel: pool.language,
+			},
+		},
+		&container.HostConfig{
+			NetworkMode:    "none",
+			ReadonlyRootfs: true,
+			Tmpfs:          sandboxTmpfs,
+			CapDrop:        []string{"ALL"},
+			SecurityOpt:    securityOpt,
+			// limit resources, the memory of the tmpfs mounts counts against the memory limit
+			Resources: container.Resources{
+				Memory:     limits.MemoryBytes,
+				MemorySwap: limits.MemoryBytes,
+				CPUQuota:   sandboxCPUQuota,
+				PidsLimit:  &limits.Processes,
+				Ulimits: []*container.Ulimit{
+					{Name: "fsize", Soft: limits.FileSizeBytes, Hard: limits.FileSizeBytes},
+					{Name: "nofile", Soft: limits.OpenFiles, Hard: limits.OpenFiles},
+				},
+			},
+		},
+		nil,
+		nil,
+		containerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.client.ContainerStart(ctx, cont.ID, container.StartOptions{})
+	if err != nil {
+		r.removeContainer(cont.ID)
+		return nil, err
+	}
+
+	return &sandboxContainer{
+		ID:          cont.ID,
+		Name:        containerName,
+		Language:    pool.language,
+		MemoryBytes: limits.MemoryBytes,
+	}, nil
+}
+
+// setMemoryLimit changes the memory limit of a container, the update is skipped when it already has the limit
+func (r *Runner) setMemoryLimit(ctx context.Context, cont *sandboxContainer, memoryBytes int64) error {
+	if cont.MemoryBytes == memoryBytes {
+		return nil
+	}
+
+	_, err := r.client.ContainerUpdate(ctx, cont.ID, container.UpdateConfig{
+		Resources: container.Resources{
+			Memory:     memoryBytes,
+			MemorySwap: memoryBytes,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update the memory limit of container %s: %w", cont.ID, err)
+	}
+
+	cont.MemoryBytes = memoryBytes
+	return nil
+}
+
+func (r *Runner) removeContainer(contID string) {
+	err := r.client.ContainerRemove(context.Background(), contID, container.RemoveOptions{
+		Force: true,
+	})
+	if err != nil {
+		logrus.Errorf("Failed to delete sandbox container %s: %v", contID, err)
+	}
+}
+
+// acquire takes a free container from the pool, waiting until one is available
+func (r *Runner) acquire(ctx context.Context, language string) (*languagePool, *sandboxContainer, error) {
+	pool, ok := r.pools[language]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported language: %s", language)
+	}
+
+	select {
+	case cont := <-pool.containers:
+		return pool, cont, nil
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("no sandbox available for %s: %w", language, ctx.Err())
+	}
+}
+
+// release puts a healthy container back into the pool
+func (r *Runner) release(pool *languagePool, cont *sandboxContainer) {
+	pool.containers <- cont
+}
+
+// replace throws away a container which can not be reused and puts a fresh one into the pool.
+// Creating the new container is retried until it works, otherwise the pool would stay a container short for good.
+func (r *Runner) replace(pool *languagePool, cont *sandboxContainer) {
+	go func() {
+		r.removeContainer(cont.ID)
+
+		delay := replaceRetryDelay
+		for {
+			newCont, err := r.createContainer(pool)
+			if err == nil {
+				pool.containers <- newCont
+				return
+			}
+
+			logrus.Errorf("Failed to replace sandbox container for %s, retrying in %s: %v", pool.language, delay, err)
+			time.Sleep(delay)
+			delay = min(2*delay, maxReplaceRetryDelay)
+		}
+	}()
+}
+
+// RunnerJob is a job directory in a pooled container holding the (compiled) code of a session.
+// After a run was stopped the container may still run the program, the job can not run again
+// and its container is replaced when the job is closed.
+type RunnerJob struct {
+	runner     *Runner
+	pool       *languagePool
+	cont       *sandboxContainer
+	jobDir     string
+	runCommand []string
+	timeout    time.Duration
+	broken     bool
+}
+
+// Prepare copies the files into a fresh job directory of a pooled container and compiles them if there
+// is a compile command. A failed compilation is returned as the result and the job is already closed.
+// The timeout of ctx covers waiting for a container and compiling.
+// The container gets the memory limit of the language, or the given one when it is higher, e.g. for a question allowing more.
+func (r *Runner) Prepare(ctx context.Context, language string, memoryBytes int64, files map[string][]byte, compileCommand, runCommand []string, timeout time.Duration) (*RunnerJob, *ExecutionResult, error) {
+	pool, cont, err := r.acquire(ctx, language)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = r.setMemoryLimit(ctx, cont, max(r.limits[language].MemoryBytes, memoryBytes))
+	if err != nil {
+		r.replace(pool, cont)
+		return nil, nil, err
+	}
+
+	job := &RunnerJob{
+		runner:     r,
+		pool:       pool,
+		cont:       cont,
+		jobDir:     path.Join(sandboxWorkDir, uuid.NewString()),
+		runCommand: runCommand,
+		timeout:    timeout,
+	}
+
+	result, err := job.compile(ctx, files, compileCommand)
+	if err != nil {
+		job.broken = true
+		job.Close()
+		if errors.Is(err, errExecutionTimedOut) {
+			return nil, &ExecutionResult{Verdict: models.TimeLimitExceeded}, nil
+		}
+		// the compiler may still be writing, the container is replaced to stop it
+		if errors.Is(err, errOutputLimitExceeded) {
+			return nil, result, nil
+		}
+		return nil, nil, err
+	}
+
+	if result != nil {
+		job.Close()
+		return nil, result, nil
+	}
+
+	return job, nil, nil
+}
+
+// compile returns a result only when the compilation failed,
+// the diagnostics of the compiler are reported as the stderr of a compile error
+func (j *RunnerJob) compile(ctx context.Context, files map[string][]byte, compileCommand []string) (*ExecutionResult, error) {
+	archive, err := createTarArchive(files)
+	if err != nil {
+		return nil, err
+	}
+
+	// copy the files into the job directory
+	_, _, exitCode, err := j.runner.exec(ctx, j.cont, []string{"sh", "-c", fmt.Sprintf("mkdir -p %s && tar -x -C %s", j.jobDir, j.jobDir)}, sandboxWorkDir, archive)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return nil, fmt.Errorf("failed to copy code into container %s", j.cont.Name)
+	}
+
+	if compileCommand == nil {
+		return nil, nil
+	}
+
+	stdout, stderr, exitCode, err := j.runner.exec(ctx, j.cont, compileCommand, j.jobDir, nil)
+	if errors.Is(err, errOutputLimitExceeded) {
+		return &ExecutionResult{
+			Stdout:    stdout,
+			Stderr:    stderr,
+			Truncated: true,
+			Verdict:   models.CompileError,
+		}, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		return &ExecutionResult{
+			Stdout:   stdout,
+			Stderr:   stderr,
+			ExitCode: exitCode,
+			Verdict:  models.CompileError,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// Run runs the compiled program once with the given stdin and arguments, stdout is captured unless a stream is given.
+// A run taking longer than runTimeout (the timeout of the job if 0) is stopped.
+func (j *RunnerJob) Run(ctx context.Context, stdin io.Reader, stdoutStream io.Writer, args []string, runTimeout time.Duration) (*ExecutionResult, error) {
+	if j.broken {
+		return nil, fmt.Errorf("sandbox container %s can not run again after a stopped run", j.cont.Name)
+	}
+
+	if runTimeout <= 0 {
+		runTimeout = j.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	result := &ExecutionResult{}
+
+	// run the code under the time utility, stdin is always attached so that programs reading input get EOF instead of hanging
+	usageFile := path.Join(j.jobDir, ".usage")
+	command := append([]string{"time", "-f", timeFormat, "-o", usageFile}, j.runCommand...)
+	command = append(command, args...)
+
+	startTime := time.Now()
+	var stdout, stderr string
+	var exitCode int
+	var err error
+	if stdoutStream != nil {
+		stderr, exitCode, err = j.runner.execStream(ctx, j.cont, command, j.jobDir, stdin, stdoutStream)
+	} else {
+		stdout, stderr, exitCode, err = j.runner.exec(ctx, j.cont, command, j.jobDir, stdin)
+	}
+	result.WallTime = time.Since(startTime).Milliseconds()
+	if errors.Is(err, errExecutionTimedOut) {
+		j.broken = true
+		result.Verdict = models.TimeLimitExceeded
+		return result, nil
+	}
+	// the program may still be writing
+	if errors.Is(err, errOutputLimitExceeded) {
+		j.broken = true
+		result.Stdout = stdout
+		result.Stderr = stderr
+		result.Truncated = true
+		result.Verdict = models.OutputLimitExceeded
+		return result, nil
+	}
+	if err != nil {
+		j.broken = true
+		return nil, err
+	}
+
+	result.Stdout = stdout
+	result.Stderr = stderr
+	result.ExitCode = exitCode
+	result.Verdict = getVerdict(exitCode, stderr)
+
+	usage, _, _, err := j.runner.exec(ctx, j.cont, []string{"cat", usageFile}, j.jobDir, nil)
+	if err == nil {
+		result.CPUTime, result.Memory = parseTimeUsage(usage)
+	}
+
+	return result, nil
+}
+
+// Close cleans up the job directory and hands the container to the next job,
+// a container which can not be cleaned up or may still run a program is replaced
+func (j *RunnerJob) Close() {
+	if j.broken {
+		j.runner.replace(j.pool, j.cont)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, _, exitCode, err := j.runner.exec(ctx, j.cont, []string{"rm", "-rf", j.jobDir}, sandboxWorkDir, nil)
+	if err != nil || exitCode != 0 {
+		j.runner.replace(j.pool, j.cont)
+	} else {
+		j.runner.release(j.pool, j.cont)
+	}
+}
+
+// exec runs a command inside the container and returns its stdout, stderr and exit code
+func (r *Runner) exec(ctx context.Context, cont *sandboxContainer, command []string, workDir string, stdin io.Reader) (string, string, int, error) {
+	// copying stops with errOutputLimitExceeded when the command writes too much
+	stdout := newLimitedBuffer(nil)
+	stderr := newLimitedBuffer(nil)
+
+	exitCode, err := r.execWithOutput(ctx, cont, command, workDir, stdin, stdout, stderr)
+	if errors.Is(err, errOutputLimitExceeded) {
+		return stdout.String(), stderr.String(), 0, err
+	}
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	return stdout.String(), stderr.String(), exitCode, nil
+}
+
+// execStream runs a command inside the container with stdin and stdout connected to the given streams
+// while they are open, as an interactive run needs. Only stderr is captured.
+func (r *Runner) execStream(ctx context.Context, cont *sandboxContainer, command []string, workDir string, stdin io.Reader, stdout io.Writer) (string, int, error) {
+	stderr := newLimitedBuffer(nil)
+
+	exitCode, err := r.execWithOutput(ctx, cont, command, workDir, stdin, stdout, stderr)
+	if errors.Is(err, errOutputLimitExceeded) {
+		return stderr.String(), 0, err
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return stderr.String(), exitCode, nil
+}
+
+// execWithOutput runs a command inside the container, copying its output into stdout and stderr.
+// The stdin is copied while the output is read, a command writing while its input is sent would block both otherwise.
+func (r *Runner) execWithOutput(ctx context.Context, cont *sandboxContainer, command []string, workDir string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	execConfig, err := r.client.ContainerExecCreate(ctx, cont.ID, container.ExecOptions{
+		Cmd:          command,
+		WorkingDir:   workDir,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	attach, err := r.client.ContainerExecAttach(ctx, execConfig.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer attach.Close()
+
+	outputCh := make(chan error, 1)
+
+	if stdin != nil {
+		// the command may exit without reading all of its input, so errors copying it do not matter
+		go func() {
+			io.Copy(attach.Conn, stdin)
+			attach.CloseWrite()
+		}()
+	}
+
+	go func() {
+		// the stream multiplexes stdout and stderr since the exec has no tty
+		_, err := stdcopy.StdCopy(stdout, stderr, attach.Reader)
+		outputCh <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return 0, errExecutionTimedOut
+	case err = <-outputCh:
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	inspect, err := r.client.ContainerExecInspect(ctx, execConfig.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return inspect.ExitCode, nil
+}
+
+// timeFormat is passed to the time utility to measure a run: elapsed, user and system seconds, max rss in KiB
+const timeFormat = "%e %U %S %M"
+
+// parseTimeUsage reads the cpu time and peak memory from the output of the time utility.
+// The measurements are the last line, anything before are messages like "Command exited with non-zero status".
+func parseTimeUsage(usage string) (cpuTime int64, memory int64) {
+	lines := strings.Split(strings.TrimSpace(usage), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) != 4 {
+		return 0, 0
+	}
+
+	userTime, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, 0
+	}
+	systemTime, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return 0, 0
+	}
+	memory, err = strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	return int64((userTime + systemTime) * 1000), memory
+}
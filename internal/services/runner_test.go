@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+)
+
+// newTestDockerExecutor returns a docker executor with a pool of a single python container,
+// the test is skipped where there is no docker daemon
+func newTestDockerExecutor(t *testing.T) *DockerExecutor {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("sandbox integration test")
+	}
+
+	cli, err := createDockerClient()
+	if err != nil {
+		t.Skipf("docker not available: %v", err)
+	}
+	_, err = cli.Ping(context.Background())
+	if err != nil {
+		t.Skipf("docker not available: %v", err)
+	}
+
+	previous := config.Config
+	config.Config = &config.Env{}
+	t.Cleanup(func() {
+		config.Config = previous
+	})
+
+	limits, err := getSandboxLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dockerfileContent, err := getDockerfileContent("python")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageName := getImageName("python")
+	err = buildImageFromDockerfile(cli, []string{imageName}, dockerfileContent)
+	if err != nil {
+		t.Fatalf("failed to build image: %v", err)
+	}
+
+	pool := &languagePool{
+		language:   "python",
+		imageName:  imageName,
+		containers: make(chan *sandboxContainer, 1),
+	}
+	runner := &Runner{
+		client:   cli,
+		poolSize: 1,
+		pools:    map[string]*languagePool{"python": pool},
+		limits:   limits,
+	}
+
+	cont, err := runner.createContainer(pool)
+	if err != nil {
+		t.Fatalf("failed to create container: %v", err)
+	}
+	pool.containers <- cont
+
+	// the container may be getting replaced in the background, wait for it to be back in the pool
+	t.Cleanup(func() {
+		select {
+		case cont := <-pool.containers:
+			runner.removeContainer(cont.ID)
+		case <-time.After(time.Minute):
+			t.Error("the sandbox container did not return to the pool")
+		}
+	})
+
+	return &DockerExecutor{runner: runner}
+}
+
+func TestDockerSandboxIsolation(t *testing.T) {
+	executor := newTestDockerExecutor(t)
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			"runs as nobody",
+			"import os\nprint(os.getuid(), os.getgid(), os.geteuid())",
+			"65534 65534 65534",
+		},
+		{
+			"has no capabilities",
+			"caps = [l.split()[1] for l in open('/proc/self/status') if l.startswith(('CapPrm', 'CapEff', 'CapAmb'))]\nprint(set(caps))",
+			"{'0000000000000000'}",
+		},
+		{
+			"can not gain privileges",
+			"print([l.split()[1] for l in open('/proc/self/status') if l.startswith('NoNewPrivs')][0])",
+			"1",
+		},
+		{
+			"root is read-only",
+			"import os\nfor path in ['/x', '/usr/x', '/etc/x', '/root/x']:\n    try:\n        open(path, 'w')\n        print('writable', path)\n    except OSError:\n        pass\nprint('done')",
+			"done",
+		},
+		{
+			"job dir and tmp are writable",
+			"open('out.txt', 'w').write('data')\nopen('/tmp/out.txt', 'w').write('tmp')\nprint(open('out.txt').read(), open('/tmp/out.txt').read())",
+			"data tmp",
+		},
+		{
+			"has no network",
+			"import socket\ntry:\n    socket.create_connection(('1.1.1.1', 53), timeout=1)\n    print('connected')\nexcept OSError:\n    print('blocked')",
+			"blocked",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ExecutionRequest{
+				Language: "python",
+				Code:     tt.code,
+				Limits:   DefaultLimits,
+				Timeout:  time.Minute,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Verdict != models.OK {
+				t.Fatalf("Execute() verdict = %s, stderr: %s", result.Verdict, result.Stderr)
+			}
+			if got := strings.TrimSpace(result.Stdout); got != tt.want {
+				t.Errorf("stdout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerSandboxLimits(t *testing.T) {
+	executor := newTestDockerExecutor(t)
+
+	allocate := func(megabytes int) string {
+		return fmt.Sprintf("data = bytearray(%d * 1024 * 1024)\nprint(len(data))", megabytes)
+	}
+
+	moreMemory := DefaultLimits
+	moreMemory.MemoryBytes = 2 * DefaultLimits.MemoryBytes
+
+	// the runs share the only container of the pool, its memory limit has to follow every job
+	tests := []struct {
+		name      string
+		code      string
+		limits    ExecutionLimits
+		timeLimit time.Duration
+		want      models.Verdict
+	}{
+		{"memory within the limit", allocate(16), DefaultLimits, time.Second, models.OK},
+		{"memory over the limit", allocate(768), DefaultLimits, 5 * time.Second, models.MemoryLimitExceeded},
+		{"job with a higher memory limit", allocate(768), moreMemory, 5 * time.Second, models.OK},
+		{"next job gets the limit of the language again", allocate(768), DefaultLimits, 5 * time.Second, models.MemoryLimitExceeded},
+		{"cpu time over the limit", "while True:\n    pass", DefaultLimits, 500 * time.Millisecond, models.TimeLimitExceeded},
+		{"too many processes", "import os\nfor _ in range(100):\n    if os.fork() == 0:\n        import time\n        time.sleep(5)\n        os._exit(0)", DefaultLimits, time.Second, models.RuntimeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ExecutionRequest{
+				Language:  "python",
+				Code:      tt.code,
+				Limits:    tt.limits,
+				Timeout:   time.Minute,
+				TimeLimit: tt.timeLimit,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Verdict != tt.want {
+				t.Errorf("Execute() verdict = %s, want %s, stderr: %s", result.Verdict, tt.want, result.Stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// QueueFullError is returned when an execution can not even wait for a slot
+type QueueFullError struct {
+	Position int // position the execution would have had in the queue
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("execution queue is full, position %d", e.Position)
+}
+
+// SchedulerLimits bound how many executions run at once and how many may wait
+type SchedulerLimits struct {
+	MaxRunning       int // executions running at once
+	MaxPerLanguage   int // executions of one language running at once
+	MaxQueued        int // executions waiting for a slot
+	MaxQueuedPerUser int // executions of one user waiting for a slot
+}
+
+type executionOwnerKey struct{}
+
+type executionOwner struct {
+	userID     string
+	background bool
+}
+
+// WithExecutionOwner tells the scheduler for which user the executions of ctx run.
+// Background executions (the judge) wait for a slot however long the queue is.
+func WithExecutionOwner(ctx context.Context, userID string, background bool) context.Context {
+	return context.WithValue(ctx, executionOwnerKey{}, executionOwner{userID: userID, background: background})
+}
+
+type withinExecutionKey struct{}
+
+// withinExecution marks executions which are part of another one holding a slot (like a custom checker),
+// they run right away since waiting for a second slot could block every slot
+func withinExecution(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withinExecutionKey{}, true)
+}
+
+type schedulerWaiter struct {
+	userID   string
+	language string
+	ready    chan struct{}
+	granted  bool
+}
+
+// Scheduler runs executions on another executor with a limited number of slots.
+// Waiting executions are queued per user and users take turns, so that one user
+// sending a lot of code can not make everyone else wait.
+type Scheduler struct {
+	executor Executor
+	limits   SchedulerLimits
+
+	mu                sync.Mutex
+	running           int
+	runningByLanguage map[string]int
+	queued            int
+	queues            map[string][]*schedulerWaiter
+	users             []string // users with waiting executions, in the order they get their turn
+}
+
+func NewScheduler(executor Executor, limits SchedulerLimits) *Scheduler {
+	return &Scheduler{
+		executor:          executor,
+		limits:            limits,
+		runningByLanguage: map[string]int{},
+		queues:            map[string][]*schedulerWaiter{},
+	}
+}
+
+func (s *Scheduler) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return execute(ctx, s, req)
+}
+
+// Prepare takes a slot which the session keeps until it is closed
+func (s *Scheduler) Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error) {
+	if ctx.Value(withinExecutionKey{}) != nil {
+		return s.executor.Prepare(ctx, req)
+	}
+
+	// executions without an owner share a single turn
+	owner, _ := ctx.Value(executionOwnerKey{}).(executionOwner)
+
+	err := s.acquire(ctx, owner, req.Language)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session, result, err := s.executor.Prepare(ctx, req)
+	if err != nil || result != nil {
+		s.release(req.Language)
+		return nil, result, err
+	}
+
+	return &scheduledSession{ExecutionSession: session, scheduler: s, language: req.Language}, nil, nil
+}
+
+type scheduledSession struct {
+	ExecutionSession
+	scheduler *Scheduler
+	language  string
+	closeOnce sync.Once
+}
+
+func (s *scheduledSession) Close() {
+	s.closeOnce.Do(func() {
+		s.ExecutionSession.Close()
+		s.scheduler.release(s.language)
+	})
+}
+
+func (s *Scheduler) canRun(language string) bool {
+	return s.running < s.limits.MaxRunning && s.runningByLanguage[language] < s.limits.MaxPerLanguage
+}
+
+func (s *Scheduler) start(language string) {
+	s.running++
+	s.runningByLanguage[language]++
+}
+
+// acquire waits until the execution gets a slot.
+// Background executions (the judge) always wait, the others fail with a QueueFullError when the queue is full.
+func (s *Scheduler) acquire(ctx context.Context, owner executionOwner, language string) error {
+	s.mu.Lock()
+
+	// after every dispatch no waiting execution can run, so a free slot is not taken from anyone
+	if s.canRun(language) {
+		s.start(language)
+		s.mu.Unlock()
+		return nil
+	}
+
+	if !owner.background && (s.queued >= s.limits.MaxQueued || len(s.queues[owner.userID]) >= s.limits.MaxQueuedPerUser) {
+		position := s.queued + 1
+		s.mu.Unlock()
+		return &QueueFullError{Position: position}
+	}
+
+	waiter := &schedulerWaiter{
+		userID:   owner.userID,
+		language: language,
+		ready:    make(chan struct{}),
+	}
+	if len(s.queues[owner.userID]) == 0 {
+		s.users = append(s.users, owner.userID)
+	}
+	s.queues[owner.userID] = append(s.queues[owner.userID], waiter)
+	s.queued++
+	s.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// the slot may have been handed over just now
+		if waiter.granted {
+			s.running--
+			s.runningByLanguage[waiter.language]--
+			s.dispatch()
+		} else {
+			s.remove(waiter)
+		}
+		return fmt.Errorf("no execution slot available: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) release(language string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	s.runningByLanguage[language]--
+	s.dispatch()
+}
+
+// dispatch hands free slots to waiting executions, users take turns and every user's executions run in order
+func (s *Scheduler) dispatch() {
+	for {
+		granted := false
+
+		for i, userID := range s.users {
+			queue := s.queues[userID]
+			index := slices.IndexFunc(queue, func(w *schedulerWaiter) bool {
+				return s.canRun(w.language)
+			})
+			if index < 0 {
+				continue
+			}
+
+			waiter := queue[index]
+			s.queues[userID] = slices.Delete(queue, index, index+1)
+			s.queued--
+
+			// the user goes to the back of the line
+			s.users = slices.Delete(s.users, i, i+1)
+			if len(s.queues[userID]) > 0 {
+				s.users = append(s.users, userID)
+			} else {
+				delete(s.queues, userID)
+			}
+
+			s.start(waiter.language)
+			waiter.granted = true
+			close(waiter.ready)
+
+			granted = true
+			break
+		}
+
+		if !granted {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) remove(waiter *schedulerWaiter) {
+	queue := s.queues[waiter.userID]
+	index := slices.Index(queue, waiter)
+	if index < 0 {
+		return
+	}
+
+	s.queues[waiter.userID] = slices.Delete(queue, index, index+1)
+	s.queued--
+
+	if len(s.queues[waiter.userID]) == 0 {
+		delete(s.queues, waiter.userID)
+		s.users = slices.DeleteFunc(s.users, func(userID string) bool {
+			return userID == waiter.userID
+		})
+	}
+}
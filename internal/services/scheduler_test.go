@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+type fakeSession struct{}
+
+func (fakeSession) Run(ctx context.Context, run RunRequest) (*ExecutionResult, error) {
+	return &ExecutionResult{Verdict: models.OK}, nil
+}
+
+func (fakeSession) Close() {}
+
+// fakeExecutor prepares sessions right away, so the scheduler is the only thing making executions wait
+type fakeExecutor struct{}
+
+func (e fakeExecutor) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
+	return execute(ctx, e, req)
+}
+
+func (fakeExecutor) Prepare(ctx context.Context, req ExecutionRequest) (ExecutionSession, *ExecutionResult, error) {
+	return fakeSession{}, nil, nil
+}
+
+type scheduledExecution struct {
+	label   string
+	session ExecutionSession
+	err     error
+}
+
+// prepareInBackground starts a Prepare and waits until it got a slot or is queued
+func prepareInBackground(t *testing.T, scheduler *Scheduler, ctx context.Context, label, language string, done chan<- scheduledExecution) {
+	t.Helper()
+
+	scheduler.mu.Lock()
+	queued := scheduler.queued
+	running := scheduler.running
+	scheduler.mu.Unlock()
+
+	go func() {
+		session, _, err := scheduler.Prepare(ctx, ExecutionRequest{Language: language})
+		done <- scheduledExecution{label: label, session: session, err: err}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		scheduler.mu.Lock()
+		changed := scheduler.queued != queued || scheduler.running != running
+		scheduler.mu.Unlock()
+		if changed {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("execution %s neither started nor was queued", label)
+}
+
+func receiveExecution(t *testing.T, done <-chan scheduledExecution) scheduledExecution {
+	t.Helper()
+
+	select {
+	case execution := <-done:
+		return execution
+	case <-time.After(5 * time.Second):
+		t.Fatal("no execution got a slot")
+	}
+	return scheduledExecution{}
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	tests := []struct {
+		name         string
+		limits       SchedulerLimits
+		queuedUsers  []string // users with an execution waiting before the last one
+		userID       string
+		background   bool
+		wantFull     bool
+		wantPosition int
+	}{
+		{"empty queue", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 2, MaxQueuedPerUser: 1}, nil, "a", false, false, 0},
+		{"queue full", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 2, MaxQueuedPerUser: 2}, []string{"a", "b"}, "c", false, true, 3},
+		{"user queue full", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 5, MaxQueuedPerUser: 1}, []string{"a"}, "a", false, true, 2},
+		{"other user still fits", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 5, MaxQueuedPerUser: 1}, []string{"a"}, "b", false, false, 0},
+		{"background waits however long the queue is", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 1, MaxQueuedPerUser: 1}, []string{"a"}, "a", true, false, 0},
+		{"no queue at all", SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 0, MaxQueuedPerUser: 0}, nil, "a", false, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewScheduler(fakeExecutor{}, tt.limits)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// the only slot is taken, everything else has to wait
+			holder, _, err := scheduler.Prepare(WithExecutionOwner(ctx, "holder", false), ExecutionRequest{Language: "python"})
+			if err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			defer holder.Close()
+
+			done := make(chan scheduledExecution, len(tt.queuedUsers)+1)
+			for _, userID := range tt.queuedUsers {
+				prepareInBackground(t, scheduler, WithExecutionOwner(ctx, userID, false), userID, "python", done)
+			}
+
+			execution := make(chan error, 1)
+			go func() {
+				_, _, err := scheduler.Prepare(WithExecutionOwner(ctx, tt.userID, tt.background), ExecutionRequest{Language: "python"})
+				execution <- err
+			}()
+
+			var queueFullError *QueueFullError
+			select {
+			case err := <-execution:
+				if !errors.As(err, &queueFullError) {
+					t.Fatalf("Prepare() error = %v, want a QueueFullError or waiting", err)
+				}
+			case <-time.After(50 * time.Millisecond):
+				// still waiting for the slot
+			}
+
+			if (queueFullError != nil) != tt.wantFull {
+				t.Fatalf("got QueueFullError %v, want full %v", queueFullError, tt.wantFull)
+			}
+			if tt.wantFull && queueFullError.Position != tt.wantPosition {
+				t.Errorf("QueueFullError.Position = %d, want %d", queueFullError.Position, tt.wantPosition)
+			}
+		})
+	}
+}
+
+func TestSchedulerUsersTakeTurns(t *testing.T) {
+	scheduler := NewScheduler(fakeExecutor{}, SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 10, MaxQueuedPerUser: 10})
+	ctx := context.Background()
+
+	holder, _, err := scheduler.Prepare(WithExecutionOwner(ctx, "holder", false), ExecutionRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	// a sends three executions before b sends one, b still does not wait for all of them
+	done := make(chan scheduledExecution, 4)
+	for _, label := range []string{"a1", "a2", "a3"} {
+		prepareInBackground(t, scheduler, WithExecutionOwner(ctx, "a", false), label, "python", done)
+	}
+	prepareInBackground(t, scheduler, WithExecutionOwner(ctx, "b", false), "b1", "python", done)
+
+	holder.Close()
+
+	want := []string{"a1", "b1", "a2", "a3"}
+	for _, label := range want {
+		execution := receiveExecution(t, done)
+		if execution.err != nil {
+			t.Fatalf("Prepare() of %s error = %v", execution.label, execution.err)
+		}
+		if execution.label != label {
+			t.Fatalf("execution %s got the slot, want %s", execution.label, label)
+		}
+		execution.session.Close()
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.running != 0 || scheduler.queued != 0 {
+		t.Errorf("running = %d, queued = %d after all executions finished", scheduler.running, scheduler.queued)
+	}
+}
+
+func TestSchedulerLanguageLimit(t *testing.T) {
+	scheduler := NewScheduler(fakeExecutor{}, SchedulerLimits{MaxRunning: 2, MaxPerLanguage: 1, MaxQueued: 10, MaxQueuedPerUser: 10})
+	ctx := WithExecutionOwner(context.Background(), "a", false)
+
+	python, _, err := scheduler.Prepare(ctx, ExecutionRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	done := make(chan scheduledExecution, 2)
+	prepareInBackground(t, scheduler, ctx, "python", "python", done)
+
+	// the waiting python execution does not hold back one of another language
+	prepareInBackground(t, scheduler, ctx, "go", "go", done)
+	execution := receiveExecution(t, done)
+	if execution.label != "go" {
+		t.Fatalf("execution %s got the slot, want go", execution.label)
+	}
+	execution.session.Close()
+
+	python.Close()
+	execution = receiveExecution(t, done)
+	if execution.label != "python" {
+		t.Fatalf("execution %s got the slot, want python", execution.label)
+	}
+	execution.session.Close()
+}
+
+func TestSchedulerCancelledWhileWaiting(t *testing.T) {
+	scheduler := NewScheduler(fakeExecutor{}, SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 10, MaxQueuedPerUser: 10})
+
+	holder, _, err := scheduler.Prepare(context.Background(), ExecutionRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(WithExecutionOwner(context.Background(), "a", false))
+	done := make(chan scheduledExecution, 1)
+	prepareInBackground(t, scheduler, ctx, "a1", "python", done)
+
+	cancel()
+	execution := receiveExecution(t, done)
+	if !errors.Is(execution.err, context.Canceled) {
+		t.Fatalf("Prepare() error = %v, want context.Canceled", execution.err)
+	}
+
+	holder.Close()
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.running != 0 || scheduler.queued != 0 || len(scheduler.users) != 0 {
+		t.Errorf("running = %d, queued = %d, users = %v after the waiting execution was cancelled", scheduler.running, scheduler.queued, scheduler.users)
+	}
+}
+
+func TestSchedulerWithinExecution(t *testing.T) {
+	scheduler := NewScheduler(fakeExecutor{}, SchedulerLimits{MaxRunning: 1, MaxPerLanguage: 1, MaxQueued: 0, MaxQueuedPerUser: 0})
+	ctx := WithExecutionOwner(context.Background(), "a", false)
+
+	holder, _, err := scheduler.Prepare(ctx, ExecutionRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer holder.Close()
+
+	// a checker of the run holding the only slot must not wait for a second one
+	checker, _, err := scheduler.Prepare(withinExecution(ctx), ExecutionRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Prepare() within an execution error = %v", err)
+	}
+	checker.Close()
+
+	if scheduler.running != 1 {
+		t.Errorf("running = %d, want 1", scheduler.running)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/config"
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
+)
+
+// RevokeSession ends a session: its refresh token stops working and its access tokens are rejected until they expire
+func RevokeSession(sessionID string) error {
+	err := models.RevokeSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	return SetCache(constants.REVOKED_SESSION_CACHE_PREFIX+sessionID, true, config.Config.ACCESS_TOKEN_TTL)
+}
+
+// RevokeUserSessions ends all sessions of a user but the given one (if any), e.g. to log out everywhere
+func RevokeUserSessions(userID, exceptSessionID string) error {
+	sessionIDs, err := models.RevokeUserSessions(userID, exceptSessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		err = SetCache(constants.REVOKED_SESSION_CACHE_PREFIX+sessionID, true, config.Config.ACCESS_TOKEN_TTL)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsSessionRevoked tells whether the access tokens of a session must be rejected.
+// The revocation only has to outlive the access tokens, after that they are expired anyway.
+func IsSessionRevoked(sessionID string) (bool, error) {
+	count, err := RedisClient.Exists(context.Background(), constants.REVOKED_SESSION_CACHE_PREFIX+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+type SubmissionEventType string
+
+const (
+	StatusEvent    SubmissionEventType = "status"
+	CompilingEvent SubmissionEventType = "compiling"
+	RunningEvent   SubmissionEventType = "running"
+	TestCaseEvent  SubmissionEventType = "test_case"
+	VerdictEvent   SubmissionEventType = "verdict"
+)
+
+// SubmissionEvent is a progress update of the judge for one submission
+type SubmissionEvent struct {
+	Type         SubmissionEventType     `json:"type"`
+	SubmissionID string                  `json:"submission_id"`
+	Status       models.SubmissionStatus `json:"status,omitempty"`
+	TestCase     int                     `json:"test_case,omitempty"` // 1-based index of the test case
+	Total        int                     `json:"total,omitempty"`
+	Result       *models.TestCaseResult  `json:"result,omitempty"`
+	Verdict      models.Verdict          `json:"verdict,omitempty"`
+	Error        string                  `json:"error,omitempty"`
+}
+
+// events go through redis so that any server instance can stream a submission judged by another one
+func getSubmissionEventsChannel(submissionID string) string {
+	return fmt.Sprintf("submission-events:%s", submissionID)
+}
+
+// PublishSubmissionEvent sends the event to everyone streaming the submission.
+// Progress events are best effort, failures are only logged.
+func PublishSubmissionEvent(event SubmissionEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logrus.Errorf("Error marshalling submission event: %v", err)
+		return
+	}
+
+	err = RedisClient.Publish(context.Background(), getSubmissionEventsChannel(event.SubmissionID), payload).Err()
+	if err != nil {
+		logrus.Errorf("Error publishing submission event for %s: %v", event.SubmissionID, err)
+	}
+}
+
+// SubscribeSubmissionEvents subscribes to the events of a submission, the caller has to close the subscription
+func SubscribeSubmissionEvents(ctx context.Context, submissionID string) (*redis.PubSub, error) {
+	subscription := RedisClient.Subscribe(ctx, getSubmissionEventsChannel(submissionID))
+
+	// wait for the subscription to be confirmed so that no event published afterwards is missed
+	_, err := subscription.Receive(ctx)
+	if err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	return subscription, nil
+}
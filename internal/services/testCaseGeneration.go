@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+// GenerationError is returned when the generator or the reference solution of a question fails,
+// it is the fault of the author and not of the sandbox
+type GenerationError struct {
+	Message string
+	Seed    *int64 // seed of the test case which failed, nil when the program did not compile
+	Verdict models.Verdict
+	Stderr  string
+}
+
+func (e *GenerationError) Error() string {
+	if e.Seed == nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Verdict)
+	}
+	return fmt.Sprintf("%s with seed %d: %s", e.Message, *e.Seed, e.Verdict)
+}
+
+// GenerateTestCases runs the generator of a question with the seeds seed, seed+1, ... and computes the
+// expected outputs with the reference solution. The generated test cases are hidden and record their seed,
+// inputs which the question already has or which were generated before are skipped.
+// Test cases of an interactive question get no output, the reference solution only has to be accepted by the interactor.
+func GenerateTestCases(ctx context.Context, question *models.Question, count int, seed int64) ([]models.TestCase, error) {
+	solution := question.ReferenceSolution
+
+	// the generator is closed before the reference solution runs, so only one execution slot is held at a time
+	testCases, err := generateInputs(ctx, question, count, seed)
+	if err != nil || len(testCases) == 0 {
+		return testCases, err
+	}
+
+	// the expected outputs are not known yet, so there is nothing for a custom checker to decide
+	exactQuestion := *question
+	exactQuestion.Checker = &models.Checker{Type: models.ExactChecker}
+
+	run, compileResult, err := prepareQuestionRun(ctx, &exactQuestion, solution.Language, solution.Code, testCases)
+	if err != nil {
+		return nil, err
+	}
+	if compileResult != nil {
+		return nil, &GenerationError{Message: "the reference solution failed to compile", Verdict: compileResult.Verdict, Stderr: compileResult.Stderr}
+	}
+	defer run.Close()
+
+	for i := range testCases {
+		result, testCaseResult, err := run.runTestCase(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		if testCaseResult == nil {
+			return nil, &GenerationError{Message: "the reference solution failed", Seed: testCases[i].Seed, Verdict: result.Verdict, Stderr: result.Stderr}
+		}
+
+		// the output of an interactive run is what the interactor reported, not an answer to check
+		if question.Mode == models.InteractiveMode {
+			if !testCaseResult.Result {
+				return nil, &GenerationError{Message: "the interactor rejected the reference solution", Seed: testCases[i].Seed, Verdict: testCaseResult.Verdict, Stderr: testCaseResult.Output}
+			}
+			continue
+		}
+
+		testCases[i].Output = testCaseResult.Output
+	}
+
+	return testCases, nil
+}
+
+// generateInputs runs the generator once per seed, the test cases it returns have no output yet
+func generateInputs(ctx context.Context, question *models.Question, count int, seed int64) ([]models.TestCase, error) {
+	generator := question.Generator
+
+	// the generator runs with the default limits of its language
+	timeLimit, memoryLimit := GetQuestionLimits(&models.Question{}, generator.Language)
+
+	session, result, err := CodeExecutor.Prepare(ctx, ExecutionRequest{
+		Language: generator.Language,
+		Code:     generator.Code,
+		Limits:   getJudgeSandboxLimits(memoryLimit),
+		Timeout:  DefaultTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		return nil, &GenerationError{Message: "the generator failed to compile", Verdict: result.Verdict, Stderr: result.Stderr}
+	}
+	defer session.Close()
+
+	inputs := map[string]bool{}
+	for _, testCase := range question.TestCases {
+		inputs[testCase.Input] = true
+	}
+
+	testCases := []models.TestCase{}
+	for i := 0; i < count; i++ {
+		testCaseSeed := seed + int64(i)
+
+		// the generator gets the seed as its only argument and prints the input of a test case
+		result, err := session.Run(ctx, RunRequest{
+			Args:        []string{strconv.FormatInt(testCaseSeed, 10)},
+			TimeLimit:   timeLimit,
+			MemoryLimit: memoryLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Verdict != models.OK {
+			return nil, &GenerationError{Message: "the generator failed", Seed: &testCaseSeed, Verdict: result.Verdict, Stderr: result.Stderr}
+		}
+
+		// a function input is written on one line like the ones of the author, stdin is kept as printed
+		input := result.Stdout
+		if question.Mode != models.StdioMode {
+			input = strings.TrimSpace(input)
+		}
+
+		if inputs[input] {
+			continue
+		}
+		inputs[input] = true
+
+		testCases = append(testCases, models.TestCase{
+			Input:      input,
+			Visibility: models.Hidden,
+			Seed:       &testCaseSeed,
+		})
+	}
+
+	return testCases, nil
+}
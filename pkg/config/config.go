@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -18,6 +19,13 @@ type Env struct {
 	JWT_TOKEN_COOKIE    string `mapstructure:"JWT_TOKEN_COOKIE"`
 	JWT_DECODED_PAYLOAD string `mapstructure:"JWT_DECODED_PAYLOAD"`
 	JWT_SECRET_KEY      string `mapstructure:"JWT_SECRET_KEY"`
+	// lifetime of the access tokens, e.g. "15m", and of the sessions without a refresh, e.g. "720h"
+	ACCESS_TOKEN_TTL     time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	REFRESH_TOKEN_TTL    time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	REFRESH_TOKEN_COOKIE string        `mapstructure:"REFRESH_TOKEN_COOKIE"`
+	// page of the frontend the password reset link opens with ?token=..., and how long the link works, e.g. "30m"
+	PASSWORD_RESET_URL       string        `mapstructure:"PASSWORD_RESET_URL"`
+	PASSWORD_RESET_TOKEN_TTL time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_TTL"`
 
 	// Email Configuration
 	SMTP_HOST     string `mapstructure:"SMTP_HOST"`
@@ -30,6 +38,10 @@ type Env struct {
 	RABBITMQ_URL string `mapstructure:"RABBITMQ_URL"`
 	QUEUE_NAME   string `mapstructure:"QUEUE_NAME"`
 
+	// Judge Queue Configuration
+	JUDGE_QUEUE_NAME string `mapstructure:"JUDGE_QUEUE_NAME"`
+	JUDGE_WORKERS    int    `mapstructure:"JUDGE_WORKERS"`
+
 	// Redis Configuration
 	REDIS_ENDPOINT string `mapstructure:"REDIS_ENDPOINT"`
 	// REDIS_USERNAME string `mapstructure:"REDIS_USERNAME"`
@@ -47,6 +59,32 @@ type Env struct {
 
 	// Mode for golang
 	MODE string `mapstructure:"MODE"`
+
+	// Code Execution Configuration
+	EXECUTOR         string `mapstructure:"EXECUTOR"` // "docker" or "process"
+	RUNNER_POOL_SIZE int    `mapstructure:"RUNNER_POOL_SIZE"`
+	// executions running at once in total and per language, and how many may wait in total and per user
+	EXECUTION_MAX_RUNNING         int `mapstructure:"EXECUTION_MAX_RUNNING"`
+	EXECUTION_MAX_PER_LANGUAGE    int `mapstructure:"EXECUTION_MAX_PER_LANGUAGE"`
+	EXECUTION_MAX_QUEUED          int `mapstructure:"EXECUTION_MAX_QUEUED"`
+	EXECUTION_MAX_QUEUED_PER_USER int `mapstructure:"EXECUTION_MAX_QUEUED_PER_USER"`
+	// JSON limits of the sandbox containers per language, e.g. {"java": {"memory": 1073741824, "processes": 256}}
+	SANDBOX_LIMITS string `mapstructure:"SANDBOX_LIMITS"`
+	// maximum size of stdout and of stderr of a run in bytes
+	MAX_OUTPUT_SIZE int `mapstructure:"MAX_OUTPUT_SIZE"`
+	// path of a seccomp profile for the sandbox containers, docker's default profile is used if empty
+	SANDBOX_SECCOMP_PROFILE string `mapstructure:"SANDBOX_SECCOMP_PROFILE"`
+	// comma separated host paths with the toolchains, mounted read-only in the root of the process sandbox
+	SANDBOX_ROOT_PATHS string `mapstructure:"SANDBOX_ROOT_PATHS"`
+	// unprivileged host user and group of the process sandbox when the server runs as root
+	SANDBOX_UID int `mapstructure:"SANDBOX_UID"`
+	SANDBOX_GID int `mapstructure:"SANDBOX_GID"`
+
+	// Plagiarism Detection Configuration
+	// how often new accepted submissions are compared, e.g. "10m"
+	PLAGIARISM_CHECK_INTERVAL time.Duration `mapstructure:"PLAGIARISM_CHECK_INTERVAL"`
+	// share of fingerprints two submissions need in common to be reported (0 to 1)
+	PLAGIARISM_THRESHOLD float64 `mapstructure:"PLAGIARISM_THRESHOLD"`
 }
 
 func NewEnv() error {
@@ -54,6 +92,27 @@ func NewEnv() error {
 	os.Chdir(path + "/../config")
 	viper.SetConfigFile(".env")
 
+	// optional settings
+	viper.SetDefault("EXECUTOR", "docker")
+	viper.SetDefault("RUNNER_POOL_SIZE", 2)
+	viper.SetDefault("MAX_OUTPUT_SIZE", 1024*1024)
+	viper.SetDefault("EXECUTION_MAX_RUNNING", 4)
+	viper.SetDefault("EXECUTION_MAX_PER_LANGUAGE", 2)
+	viper.SetDefault("EXECUTION_MAX_QUEUED", 50)
+	viper.SetDefault("EXECUTION_MAX_QUEUED_PER_USER", 3)
+	viper.SetDefault("SANDBOX_ROOT_PATHS", "/bin,/sbin,/lib,/lib32,/lib64,/usr,/etc,/opt")
+	viper.SetDefault("SANDBOX_UID", 65534)
+	viper.SetDefault("SANDBOX_GID", 65534)
+	viper.SetDefault("JUDGE_QUEUE_NAME", "judge")
+	viper.SetDefault("JUDGE_WORKERS", 2)
+	viper.SetDefault("PLAGIARISM_CHECK_INTERVAL", "10m")
+	viper.SetDefault("PLAGIARISM_THRESHOLD", 0.8)
+	viper.SetDefault("ACCESS_TOKEN_TTL", "15m")
+	viper.SetDefault("REFRESH_TOKEN_TTL", "720h")
+	viper.SetDefault("REFRESH_TOKEN_COOKIE", "refresh_token")
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:3000/reset-password")
+	viper.SetDefault("PASSWORD_RESET_TOKEN_TTL", "30m")
+
 	err := viper.ReadInConfig()
 	if err != nil {
 		return fmt.Errorf("can't find the environment file : %v", err)
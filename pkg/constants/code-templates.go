@@ -2,48 +2,39 @@ package constants
 
 const PYTHON_CODE_TEMPLATE = `
 import json
+import sys
 
 %s
 
-results = []
-testcases = %s
+# the judge passes the test case to run on stdin
+tc = json.loads(sys.stdin.read())
 
-for tc in testcases:
-	args = [arg.split("=")[1].strip() for arg in tc["input"].split(";")]
+args = [arg.split("=")[1].strip() for arg in tc["input"].split(";")]
 
-	inp = "("
-	for arg in args:
-		inp += arg + ", "
-	inp += ")"
+inp = "("
+for arg in args:
+	inp += arg + ", "
+inp += ")"
 
-	func_name = %s
+func_name = %s
 
-	output = eval(f"{func_name.__name__}{inp}")
+output = eval(f"{func_name.__name__}{inp}")
 
-	res = {
-		"input": tc["input"],
-		"output": output,
-		"expected": tc["output"],
-	}
-
-	if f"{output}" != tc["output"]:
-		res["result"] = False
-	else:
-		res["result"] = True
-	
-	results.append(res)
+res = {
+	"input": tc["input"],
+	"output": f"{output}",
+}
 
-print(json.dumps(results))
+print(json.dumps([res]))
 `
 
 const JAVASCRIPT_CODE_TEMPLATE = `
 %s
 
-const results = [];
-let testcases = %s
-
-for (const tc of testcases) {
-    const args = tc.input.split(";").map(arg => arg.split("=")[1])
+// the judge passes the test case to run on stdin
+{
+	const tc = JSON.parse(require("fs").readFileSync(0, "utf8"))
+	const args = tc.input.split(";").map(arg => arg.split("=")[1])
 
 	let input = "("
 	for(const arg of args) {
@@ -58,22 +49,1008 @@ for (const tc of testcases) {
 	const res = {
 		"input": tc.input,
 		"output": output.toString(),
-		"expected": tc.output,
 	}
-	
-	if (output.toString() !== tc.output) {
-		res["result"] = false
+
+	console.log(JSON.stringify([res]));
+}
+`
+
+
+// the Go, C++ and Java harnesses read the test case to run as JSON with the arguments of the call from stdin,
+// parse them into the parameter types of the function and print the output as compact JSON
+const GO_CODE_TEMPLATE = `package main
+
+import (
+	__bytes "bytes"
+	__json "encoding/json"
+	__fmt "fmt"
+	__io "io"
+	__os "os"
+	__reflect "reflect"
+)
+
+%s
+
+type ListNode struct {
+	Val  int
+	Next *ListNode
+}
+
+type TreeNode struct {
+	Val   int
+	Left  *TreeNode
+	Right *TreeNode
+}
+
+func __toJson(value interface{}) string {
+	var buffer __bytes.Buffer
+	encoder := __json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		panic(err)
+	}
+	return string(__bytes.TrimRight(buffer.Bytes(), "\n"))
+}
+
+func __unmarshal(raw __json.RawMessage, value interface{}) {
+	if err := __json.Unmarshal(raw, value); err != nil {
+		panic(err)
+	}
+}
+
+// __decode reads a JSON value into the type of a parameter, lists are arrays and trees are in level order
+func __decode(raw __json.RawMessage, t __reflect.Type) __reflect.Value {
+	switch t {
+	case __reflect.TypeOf((*ListNode)(nil)):
+		var values []int
+		__unmarshal(raw, &values)
+		var head *ListNode
+		for i := len(values) - 1; i >= 0; i-- {
+			head = &ListNode{Val: values[i], Next: head}
+		}
+		return __reflect.ValueOf(head)
+	case __reflect.TypeOf((*TreeNode)(nil)):
+		var values []*int
+		__unmarshal(raw, &values)
+		if len(values) == 0 || values[0] == nil {
+			return __reflect.ValueOf((*TreeNode)(nil))
+		}
+		root := &TreeNode{Val: *values[0]}
+		queue := []*TreeNode{root}
+		for i := 1; i < len(values); i += 2 {
+			node := queue[0]
+			queue = queue[1:]
+			if values[i] != nil {
+				node.Left = &TreeNode{Val: *values[i]}
+				queue = append(queue, node.Left)
+			}
+			if i+1 < len(values) && values[i+1] != nil {
+				node.Right = &TreeNode{Val: *values[i+1]}
+				queue = append(queue, node.Right)
+			}
+		}
+		return __reflect.ValueOf(root)
+	}
+
+	switch t.Kind() {
+	case __reflect.Slice:
+		var items []__json.RawMessage
+		__unmarshal(raw, &items)
+		slice := __reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).Set(__decode(item, t.Elem()))
+		}
+		return slice
+	case __reflect.Uint8:
+		// chars are bytes
+		var text string
+		__unmarshal(raw, &text)
+		value := __reflect.New(t).Elem()
+		if len(text) > 0 {
+			value.SetUint(uint64(text[0]))
+		}
+		return value
+	}
+
+	value := __reflect.New(t)
+	__unmarshal(raw, value.Interface())
+	return value.Elem()
+}
+
+func __encode(value __reflect.Value) interface{} {
+	if value.Kind() == __reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch node := value.Interface().(type) {
+	case *ListNode:
+		values := []int{}
+		for ; node != nil; node = node.Next {
+			values = append(values, node.Val)
+		}
+		return values
+	case *TreeNode:
+		values := []interface{}{}
+		queue := []*TreeNode{node}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if current == nil {
+				values = append(values, nil)
+				continue
+			}
+			values = append(values, current.Val)
+			queue = append(queue, current.Left, current.Right)
+		}
+		for len(values) > 0 && values[len(values)-1] == nil {
+			values = values[:len(values)-1]
+		}
+		return values
+	}
+
+	switch value.Kind() {
+	case __reflect.Slice, __reflect.Array:
+		values := make([]interface{}, value.Len())
+		for i := range values {
+			values[i] = __encode(value.Index(i))
+		}
+		return values
+	case __reflect.Uint8:
+		return string([]byte{byte(value.Uint())})
+	}
+
+	return value.Interface()
+}
+
+func main() {
+	raw, err := __io.ReadAll(__os.Stdin)
+	if err != nil {
+		panic(err)
+	}
+
+	var tc struct {
+		Input string              ` + "`json:\"input\"`" + `
+		Args  []__json.RawMessage ` + "`json:\"args\"`" + `
+	}
+	__unmarshal(raw, &tc)
+
+	function := __reflect.ValueOf(%s)
+
+	args := make([]__reflect.Value, len(tc.Args))
+	for i, arg := range tc.Args {
+		args[i] = __decode(arg, function.Type().In(i))
+	}
+
+	output := "null"
+	if outputs := function.Call(args); len(outputs) > 0 {
+		output = __toJson(__encode(outputs[0]))
+	}
+
+	__fmt.Println(__toJson([]map[string]interface{}{{
+		"input":  tc.Input,
+		"output": output,
+	}}))
+}
+`
+
+const CPP_CODE_TEMPLATE = `#include <bits/stdc++.h>
+using namespace std;
+
+struct ListNode {
+	int val;
+	ListNode* next;
+	ListNode() : val(0), next(nullptr) {}
+	ListNode(int x) : val(x), next(nullptr) {}
+	ListNode(int x, ListNode* next) : val(x), next(next) {}
+};
+
+struct TreeNode {
+	int val;
+	TreeNode* left;
+	TreeNode* right;
+	TreeNode() : val(0), left(nullptr), right(nullptr) {}
+	TreeNode(int x) : val(x), left(nullptr), right(nullptr) {}
+	TreeNode(int x, TreeNode* left, TreeNode* right) : val(x), left(left), right(right) {}
+};
+
+%s
+
+namespace judge {
+
+struct Json {
+	enum Type { Null, Bool, Number, String, Array, Object } type = Null;
+	bool boolean = false;
+	string text; // the raw number or the decoded string
+	vector<Json> items;
+	vector<pair<string, Json>> fields;
+
+	const Json& operator[](const string& key) const {
+		for (const auto& field : fields) {
+			if (field.first == key) return field.second;
+		}
+		throw runtime_error("missing key " + key);
+	}
+};
+
+void appendUtf8(string& out, unsigned code) {
+	if (code < 0x80) {
+		out += char(code);
+	} else if (code < 0x800) {
+		out += char(0xC0 | (code >> 6));
+		out += char(0x80 | (code & 0x3F));
+	} else if (code < 0x10000) {
+		out += char(0xE0 | (code >> 12));
+		out += char(0x80 | ((code >> 6) & 0x3F));
+		out += char(0x80 | (code & 0x3F));
+	} else {
+		out += char(0xF0 | (code >> 18));
+		out += char(0x80 | ((code >> 12) & 0x3F));
+		out += char(0x80 | ((code >> 6) & 0x3F));
+		out += char(0x80 | (code & 0x3F));
+	}
+}
+
+struct Parser {
+	const string& s;
+	size_t i = 0;
+
+	explicit Parser(const string& s) : s(s) {}
+
+	void skip() {
+		while (i < s.size() && isspace((unsigned char)s[i])) i++;
+	}
+
+	Json parse() {
+		skip();
+		Json value;
+		if (s[i] == '{') {
+			value.type = Json::Object;
+			i++;
+			skip();
+			if (s[i] == '}') { i++; return value; }
+			while (true) {
+				skip();
+				string key = parseString();
+				skip();
+				i++; // ':'
+				value.fields.emplace_back(key, parse());
+				skip();
+				if (s[i++] == '}') return value;
+			}
+		}
+		if (s[i] == '[') {
+			value.type = Json::Array;
+			i++;
+			skip();
+			if (s[i] == ']') { i++; return value; }
+			while (true) {
+				value.items.push_back(parse());
+				skip();
+				if (s[i++] == ']') return value;
+			}
+		}
+		if (s[i] == '"') {
+			value.type = Json::String;
+			value.text = parseString();
+			return value;
+		}
+		if (s.compare(i, 4, "true") == 0) {
+			value.type = Json::Bool;
+			value.boolean = true;
+			i += 4;
+			return value;
+		}
+		if (s.compare(i, 5, "false") == 0) {
+			value.type = Json::Bool;
+			i += 5;
+			return value;
+		}
+		if (s.compare(i, 4, "null") == 0) {
+			i += 4;
+			return value;
+		}
+		size_t start = i;
+		while (i < s.size() && strchr("+-.eE0123456789", s[i])) i++;
+		value.type = Json::Number;
+		value.text = s.substr(start, i - start);
+		return value;
+	}
+
+	string parseString() {
+		string out;
+		i++; // opening quote
+		while (s[i] != '"') {
+			char c = s[i++];
+			if (c != '\\') {
+				out += c;
+				continue;
+			}
+			char escaped = s[i++];
+			switch (escaped) {
+			case 'n': out += '\n'; break;
+			case 't': out += '\t'; break;
+			case 'r': out += '\r'; break;
+			case 'b': out += '\b'; break;
+			case 'f': out += '\f'; break;
+			case 'u': {
+				unsigned code = stoul(s.substr(i, 4), nullptr, 16);
+				i += 4;
+				// surrogate pair
+				if (code >= 0xD800 && code < 0xDC00 && s.compare(i, 2, "\\u") == 0) {
+					unsigned low = stoul(s.substr(i + 2, 4), nullptr, 16);
+					i += 6;
+					code = 0x10000 + ((code - 0xD800) << 10) + (low - 0xDC00);
+				}
+				appendUtf8(out, code);
+				break;
+			}
+			default: out += escaped;
+			}
+		}
+		i++; // closing quote
+		return out;
+	}
+};
+
+// lists are JSON arrays and trees are arrays in level order
+template <class T>
+T fromJson(const Json& json) {
+	if constexpr (is_same_v<T, ListNode*>) {
+		ListNode* head = nullptr;
+		for (auto item = json.items.rbegin(); item != json.items.rend(); item++) head = new ListNode(stoi(item->text), head);
+		return head;
+	} else if constexpr (is_same_v<T, TreeNode*>) {
+		const auto& values = json.items;
+		if (values.empty() || values[0].type == Json::Null) return nullptr;
+		TreeNode* root = new TreeNode(stoi(values[0].text));
+		queue<TreeNode*> nodes;
+		nodes.push(root);
+		for (size_t k = 1; k < values.size(); k += 2) {
+			TreeNode* node = nodes.front();
+			nodes.pop();
+			if (values[k].type != Json::Null) {
+				node->left = new TreeNode(stoi(values[k].text));
+				nodes.push(node->left);
+			}
+			if (k + 1 < values.size() && values[k + 1].type != Json::Null) {
+				node->right = new TreeNode(stoi(values[k + 1].text));
+				nodes.push(node->right);
+			}
+		}
+		return root;
+	} else if constexpr (is_same_v<T, bool>) {
+		return json.boolean;
+	} else if constexpr (is_same_v<T, char>) {
+		return json.text.empty() ? '\0' : json.text[0];
+	} else if constexpr (is_integral_v<T>) {
+		return static_cast<T>(stoll(json.text));
+	} else if constexpr (is_floating_point_v<T>) {
+		return static_cast<T>(stod(json.text));
+	} else if constexpr (is_same_v<T, string>) {
+		return json.text;
 	} else {
-		res["result"] = true
+		T out;
+		for (const auto& item : json.items) out.push_back(fromJson<typename T::value_type>(item));
+		return out;
 	}
+}
+
+string quote(const string& s) {
+	string out = "\"";
+	for (unsigned char c : s) {
+		switch (c) {
+		case '"': out += "\\\""; break;
+		case '\\': out += "\\\\"; break;
+		case '\n': out += "\\n"; break;
+		case '\r': out += "\\r"; break;
+		case '\t': out += "\\t"; break;
+		default:
+			if (c < 0x20) {
+				const char* hex = "0123456789abcdef";
+				out += "\\u00";
+				out += hex[c >> 4];
+				out += hex[c & 0xF];
+			} else {
+				out += char(c);
+			}
+		}
+	}
+	return out + "\"";
+}
+
+template <class T>
+string toJson(const T& value) {
+	if constexpr (is_same_v<T, ListNode*>) {
+		string out = "[";
+		for (ListNode* node = value; node != nullptr; node = node->next) {
+			if (node != value) out += ",";
+			out += to_string(node->val);
+		}
+		return out + "]";
+	} else if constexpr (is_same_v<T, TreeNode*>) {
+		vector<string> values;
+		queue<TreeNode*> nodes;
+		nodes.push(value);
+		while (!nodes.empty()) {
+			TreeNode* node = nodes.front();
+			nodes.pop();
+			if (node == nullptr) {
+				values.push_back("null");
+				continue;
+			}
+			values.push_back(to_string(node->val));
+			nodes.push(node->left);
+			nodes.push(node->right);
+		}
+		while (!values.empty() && values.back() == "null") values.pop_back();
+		string out = "[";
+		for (size_t k = 0; k < values.size(); k++) {
+			if (k > 0) out += ",";
+			out += values[k];
+		}
+		return out + "]";
+	} else if constexpr (is_same_v<T, bool>) {
+		return value ? "true" : "false";
+	} else if constexpr (is_same_v<T, char>) {
+		return quote(string(1, value));
+	} else if constexpr (is_integral_v<T>) {
+		return to_string(value);
+	} else if constexpr (is_floating_point_v<T>) {
+		char buffer[64];
+		auto result = to_chars(buffer, buffer + sizeof(buffer), value);
+		return string(buffer, result.ptr);
+	} else if constexpr (is_convertible_v<T, string>) {
+		return quote(string(value));
+	} else {
+		string out = "[";
+		bool first = true;
+		for (const auto& item : value) {
+			if (!first) out += ",";
+			first = false;
+			out += toJson(static_cast<typename T::value_type>(item));
+		}
+		return out + "]";
+	}
+}
+
+template <class R, class... A, size_t... I>
+string call(R (*function)(A...), const Json& args, index_sequence<I...>) {
+	tuple<decay_t<A>...> values(fromJson<decay_t<A>>(args.items.at(I))...);
+	if constexpr (is_void_v<R>) {
+		function(get<I>(values)...);
+		return "null";
+	} else {
+		return toJson(function(get<I>(values)...));
+	}
+}
 
-    results.push(res);
+template <class R, class... A>
+string call(R (*function)(A...), const Json& args) {
+	return call(function, args, index_sequence_for<A...>{});
 }
 
-console.log(JSON.stringify(results));
+} // namespace judge
+
+int main() {
+	string raw((istreambuf_iterator<char>(cin)), istreambuf_iterator<char>());
+	judge::Json tc = judge::Parser(raw).parse();
+
+	string output = judge::call(%s, tc["args"]);
+
+	cout << "[{\"input\":" + judge::quote(tc["input"].text) +
+		",\"output\":" + judge::quote(output) + "}]" << endl;
+	return 0;
+}
+`
+
+// the Java harness calls the method of the user's Solution class through reflection
+const JAVA_CODE_TEMPLATE = `import java.io.*;
+import java.lang.reflect.*;
+import java.math.BigDecimal;
+import java.util.*;
+
+%s
+
+class ListNode {
+	int val;
+	ListNode next;
+	ListNode() {}
+	ListNode(int val) { this.val = val; }
+	ListNode(int val, ListNode next) { this.val = val; this.next = next; }
+}
+
+class TreeNode {
+	int val;
+	TreeNode left;
+	TreeNode right;
+	TreeNode() {}
+	TreeNode(int val) { this.val = val; }
+	TreeNode(int val, TreeNode left, TreeNode right) { this.val = val; this.left = left; this.right = right; }
+}
+
+public class Main {
+	static final String FUNCTION = "%s";
+
+	static class Parser {
+		final String s;
+		int i = 0;
+
+		Parser(String s) {
+			this.s = s;
+		}
+
+		void skip() {
+			while (i < s.length() && Character.isWhitespace(s.charAt(i))) i++;
+		}
+
+		Object parse() {
+			skip();
+			char c = s.charAt(i);
+			if (c == '{') {
+				Map<String, Object> object = new LinkedHashMap<>();
+				i++;
+				skip();
+				if (s.charAt(i) == '}') {
+					i++;
+					return object;
+				}
+				while (true) {
+					skip();
+					String key = parseString();
+					skip();
+					i++; // ':'
+					object.put(key, parse());
+					skip();
+					if (s.charAt(i++) == '}') return object;
+				}
+			}
+			if (c == '[') {
+				List<Object> array = new ArrayList<>();
+				i++;
+				skip();
+				if (s.charAt(i) == ']') {
+					i++;
+					return array;
+				}
+				while (true) {
+					array.add(parse());
+					skip();
+					if (s.charAt(i++) == ']') return array;
+				}
+			}
+			if (c == '"') return parseString();
+			if (s.startsWith("true", i)) {
+				i += 4;
+				return Boolean.TRUE;
+			}
+			if (s.startsWith("false", i)) {
+				i += 5;
+				return Boolean.FALSE;
+			}
+			if (s.startsWith("null", i)) {
+				i += 4;
+				return null;
+			}
+			int start = i;
+			while (i < s.length() && "+-.eE0123456789".indexOf(s.charAt(i)) >= 0) i++;
+			return new BigDecimal(s.substring(start, i));
+		}
+
+		String parseString() {
+			StringBuilder out = new StringBuilder();
+			i++; // opening quote
+			while (s.charAt(i) != '"') {
+				char c = s.charAt(i++);
+				if (c != '\\') {
+					out.append(c);
+					continue;
+				}
+				char escaped = s.charAt(i++);
+				switch (escaped) {
+					case 'n': out.append('\n'); break;
+					case 't': out.append('\t'); break;
+					case 'r': out.append('\r'); break;
+					case 'b': out.append('\b'); break;
+					case 'f': out.append('\f'); break;
+					case 'u':
+						out.append((char) Integer.parseInt(s.substring(i, i + 4), 16));
+						i += 4;
+						break;
+					default: out.append(escaped);
+				}
+			}
+			i++; // closing quote
+			return out.toString();
+		}
+	}
+
+	static Object fromJson(Object value, Type type) {
+		if (value == null) return null;
+
+		if (type instanceof ParameterizedType) {
+			ParameterizedType parameterized = (ParameterizedType) type;
+			Class<?> raw = (Class<?>) parameterized.getRawType();
+			Type item = parameterized.getActualTypeArguments()[0];
+
+			List<Object> list = new ArrayList<>();
+			for (Object element : (List<?>) value) list.add(fromJson(element, item));
+			if (Set.class.isAssignableFrom(raw)) return new LinkedHashSet<>(list);
+			if (Deque.class.isAssignableFrom(raw) || Queue.class.isAssignableFrom(raw)) return new ArrayDeque<>(list);
+			return list;
+		}
+
+		Class<?> c = (Class<?>) type;
+		// lists are JSON arrays and trees are arrays in level order
+		if (c == ListNode.class) {
+			List<?> values = (List<?>) value;
+			ListNode head = null;
+			for (int k = values.size() - 1; k >= 0; k--) head = new ListNode(((BigDecimal) values.get(k)).intValue(), head);
+			return head;
+		}
+		if (c == TreeNode.class) {
+			List<?> values = (List<?>) value;
+			if (values.isEmpty() || values.get(0) == null) return null;
+			TreeNode root = new TreeNode(((BigDecimal) values.get(0)).intValue());
+			Deque<TreeNode> nodes = new ArrayDeque<>();
+			nodes.add(root);
+			for (int k = 1; k < values.size(); k += 2) {
+				TreeNode node = nodes.poll();
+				if (values.get(k) != null) {
+					node.left = new TreeNode(((BigDecimal) values.get(k)).intValue());
+					nodes.add(node.left);
+				}
+				if (k + 1 < values.size() && values.get(k + 1) != null) {
+					node.right = new TreeNode(((BigDecimal) values.get(k + 1)).intValue());
+					nodes.add(node.right);
+				}
+			}
+			return root;
+		}
+		if (c.isArray()) {
+			List<?> elements = (List<?>) value;
+			Object array = Array.newInstance(c.getComponentType(), elements.size());
+			for (int k = 0; k < elements.size(); k++) Array.set(array, k, fromJson(elements.get(k), c.getComponentType()));
+			return array;
+		}
+		if (c == int.class || c == Integer.class) return ((BigDecimal) value).intValue();
+		if (c == long.class || c == Long.class) return ((BigDecimal) value).longValue();
+		if (c == double.class || c == Double.class) return ((BigDecimal) value).doubleValue();
+		if (c == float.class || c == Float.class) return ((BigDecimal) value).floatValue();
+		if (c == boolean.class || c == Boolean.class) return value;
+		if (c == char.class || c == Character.class) return ((String) value).charAt(0);
+		if (c == String.class || c == Object.class || c.isInstance(value)) return value;
+
+		throw new IllegalArgumentException("unsupported parameter type " + c.getName());
+	}
+
+	static String formatDouble(double d) {
+		if (d == Math.rint(d) && Math.abs(d) < 1e15) return Long.toString((long) d);
+		if (Math.abs(d) >= 1e-6 && Math.abs(d) < 1e21) return new BigDecimal(Double.toString(d)).stripTrailingZeros().toPlainString();
+		return Double.toString(d);
+	}
+
+	static String quote(String s) {
+		StringBuilder out = new StringBuilder("\"");
+		for (char c : s.toCharArray()) {
+			switch (c) {
+				case '"': out.append("\\\""); break;
+				case '\\': out.append("\\\\"); break;
+				case '\n': out.append("\\n"); break;
+				case '\r': out.append("\\r"); break;
+				case '\t': out.append("\\t"); break;
+				default:
+					if (c < 0x20) {
+						String hex = Integer.toHexString(c);
+						out.append("\\u").append("0000".substring(hex.length())).append(hex);
+					} else {
+						out.append(c);
+					}
+			}
+		}
+		return out.append('"').toString();
+	}
+
+	static String toJson(Object value) {
+		if (value == null) return "null";
+		if (value instanceof Boolean) return value.toString();
+		if (value instanceof Double || value instanceof Float) return formatDouble(((Number) value).doubleValue());
+		if (value instanceof Number) return value.toString();
+		if (value instanceof Character || value instanceof String) return quote(value.toString());
+
+		StringBuilder out = new StringBuilder("[");
+		if (value instanceof ListNode) {
+			for (ListNode node = (ListNode) value; node != null; node = node.next) {
+				if (node != value) out.append(',');
+				out.append(node.val);
+			}
+		} else if (value instanceof TreeNode) {
+			List<String> values = new ArrayList<>();
+			Deque<TreeNode> nodes = new LinkedList<>();
+			nodes.add((TreeNode) value);
+			while (!nodes.isEmpty()) {
+				TreeNode node = nodes.poll();
+				if (node == null) {
+					values.add("null");
+					continue;
+				}
+				values.add(Integer.toString(node.val));
+				nodes.add(node.left);
+				nodes.add(node.right);
+			}
+			while (!values.isEmpty() && values.get(values.size() - 1).equals("null")) values.remove(values.size() - 1);
+			out.append(String.join(",", values));
+		} else if (value.getClass().isArray()) {
+			for (int k = 0; k < Array.getLength(value); k++) {
+				if (k > 0) out.append(',');
+				out.append(toJson(Array.get(value, k)));
+			}
+		} else if (value instanceof Iterable) {
+			boolean first = true;
+			for (Object element : (Iterable<?>) value) {
+				if (!first) out.append(',');
+				first = false;
+				out.append(toJson(element));
+			}
+		} else {
+			return quote(value.toString());
+		}
+		return out.append(']').toString();
+	}
+
+	public static void main(String[] args) throws Throwable {
+		System.setOut(new PrintStream(new FileOutputStream(FileDescriptor.out), true, "UTF-8"));
+
+		Method method = null;
+		for (Method candidate : Solution.class.getDeclaredMethods()) {
+			if (candidate.getName().equals(FUNCTION)) {
+				method = candidate;
+				break;
+			}
+		}
+		if (method == null) throw new NoSuchMethodException(FUNCTION);
+		method.setAccessible(true);
+
+		Object instance = null;
+		if (!Modifier.isStatic(method.getModifiers())) {
+			Constructor<?> constructor = Solution.class.getDeclaredConstructor();
+			constructor.setAccessible(true);
+			instance = constructor.newInstance();
+		}
+
+		Type[] types = method.getGenericParameterTypes();
+		Map<?, ?> tc = (Map<?, ?>) new Parser(new String(System.in.readAllBytes(), "UTF-8")).parse();
+		List<?> arguments = (List<?>) tc.get("args");
+
+		Object[] values = new Object[types.length];
+		for (int p = 0; p < types.length; p++) values[p] = fromJson(arguments.get(p), types[p]);
+
+		Object returned;
+		try {
+			returned = method.invoke(instance, values);
+		} catch (InvocationTargetException e) {
+			throw e.getCause();
+		}
+
+		Class<?> returnType = method.getReturnType();
+		String output = returnType == void.class ? "null" : toJson(returned);
+		if (returned == null && (returnType == ListNode.class || returnType == TreeNode.class)) output = "[]";
+
+		System.out.println("[{\"input\":" + quote((String) tc.get("input")) + ",\"output\":" + quote(output) + "}]");
+	}
+}
+`
+
+// the signature harnesses get the signature as JSON and read the test case to run from stdin, decode every
+// argument by the type of its parameter and print the output encoded by the return type as compact JSON
+const PYTHON_SIGNATURE_CODE_TEMPLATE = `import json
+import sys
+from typing import *
+
+
+class ListNode:
+    def __init__(self, val=0, next=None):
+        self.val = val
+        self.next = next
+
+
+class TreeNode:
+    def __init__(self, val=0, left=None, right=None):
+        self.val = val
+        self.left = left
+        self.right = right
+
+
+%[1]s
+
+
+# lists are JSON arrays and trees are arrays in level order
+def __decode(value, type):
+    if value is None:
+        return None
+    if type.endswith("[]"):
+        return [__decode(item, type[:-2]) for item in value]
+    if type == "ListNode":
+        head = None
+        for item in reversed(value):
+            head = ListNode(item, head)
+        return head
+    if type == "TreeNode":
+        if not value or value[0] is None:
+            return None
+        root = TreeNode(value[0])
+        nodes = [root]
+        front = 0
+        for i in range(1, len(value), 2):
+            node = nodes[front]
+            front += 1
+            if value[i] is not None:
+                node.left = TreeNode(value[i])
+                nodes.append(node.left)
+            if i + 1 < len(value) and value[i + 1] is not None:
+                node.right = TreeNode(value[i + 1])
+                nodes.append(node.right)
+        return root
+    if type == "double":
+        return float(value)
+    if type in ("int", "long"):
+        return int(value)
+    return value
+
+
+def __encode(value, type):
+    if type.endswith("[]"):
+        return [__encode(item, type[:-2]) for item in (value or [])]
+    if type == "ListNode":
+        values = []
+        while value is not None:
+            values.append(value.val)
+            value = value.next
+        return values
+    if type == "TreeNode":
+        values = []
+        nodes = [value]
+        front = 0
+        while front < len(nodes):
+            node = nodes[front]
+            front += 1
+            if node is None:
+                values.append(None)
+                continue
+            values.append(node.val)
+            nodes.append(node.left)
+            nodes.append(node.right)
+        while values and values[-1] is None:
+            values.pop()
+        return values
+    if value is None:
+        return None
+    if type == "double":
+        value = float(value)
+        return int(value) if value.is_integer() and abs(value) < 1e15 else value
+    if type == "bool":
+        return bool(value)
+    return value
+
+
+__harness = json.loads(%[2]s)
+
+__function = globals().get(__harness["functionName"])
+if __function is None:
+    __function = getattr(globals()["Solution"](), __harness["functionName"])
+
+# the judge passes the test case to run on stdin
+__tc = json.loads(sys.stdin.read())
+
+__args = [__decode(arg, type) for arg, type in zip(__tc["args"], __harness["parameterTypes"])]
+__output = json.dumps(__encode(__function(*__args), __harness["returnType"]), ensure_ascii=False, separators=(",", ":"))
+
+print(json.dumps([{
+    "input": __tc["input"],
+    "output": __output,
+}]))
+`
+
+const JAVASCRIPT_SIGNATURE_CODE_TEMPLATE = `class ListNode {
+	constructor(val = 0, next = null) {
+		this.val = val
+		this.next = next
+	}
+}
+
+class TreeNode {
+	constructor(val = 0, left = null, right = null) {
+		this.val = val
+		this.left = left
+		this.right = right
+	}
+}
+
+%[1]s
+
+// lists are JSON arrays and trees are arrays in level order
+const __decode = (value, type) => {
+	if (value === null) return null
+	if (type.endsWith("[]")) return value.map(item => __decode(item, type.slice(0, -2)))
+	if (type === "ListNode") {
+		let head = null
+		for (let i = value.length - 1; i >= 0; i--) head = new ListNode(value[i], head)
+		return head
+	}
+	if (type === "TreeNode") {
+		if (value.length === 0 || value[0] === null) return null
+		const root = new TreeNode(value[0])
+		const nodes = [root]
+		let front = 0
+		for (let i = 1; i < value.length; i += 2) {
+			const node = nodes[front++]
+			if (value[i] !== null) {
+				node.left = new TreeNode(value[i])
+				nodes.push(node.left)
+			}
+			if (i + 1 < value.length && value[i + 1] !== null) {
+				node.right = new TreeNode(value[i + 1])
+				nodes.push(node.right)
+			}
+		}
+		return root
+	}
+	return value
+}
+
+const __encode = (value, type) => {
+	if (type.endsWith("[]")) return (value || []).map(item => __encode(item, type.slice(0, -2)))
+	if (type === "ListNode") {
+		const values = []
+		for (let node = value; node; node = node.next) values.push(node.val)
+		return values
+	}
+	if (type === "TreeNode") {
+		const values = []
+		const nodes = [value]
+		for (let front = 0; front < nodes.length; front++) {
+			const node = nodes[front]
+			if (!node) {
+				values.push(null)
+				continue
+			}
+			values.push(node.val)
+			nodes.push(node.left, node.right)
+		}
+		while (values.length > 0 && values[values.length - 1] === null) values.pop()
+		return values
+	}
+	if (value === undefined) return null
+	if (type === "bool") return Boolean(value)
+	return value
+}
+
+const __harness = JSON.parse(%[2]s)
+const __function = typeof %[3]s === "function" ? %[3]s : (...args) => new Solution().%[3]s(...args)
+
+// the judge passes the test case to run on stdin
+const __tc = JSON.parse(require("fs").readFileSync(0, "utf8"))
+
+const __args = __tc.args.map((arg, i) => __decode(arg, __harness.parameterTypes[i]))
+const __output = JSON.stringify(__encode(__function(...__args), __harness.returnType))
+
+console.log(JSON.stringify([{
+	"input": __tc.input,
+	"output": __output,
+}]))
 `
 
 var GOLANG_CODE_TEMPLATE = map[string]string{
 	"python":     PYTHON_CODE_TEMPLATE,
 	"javascript": JAVASCRIPT_CODE_TEMPLATE,
-}
\ No newline at end of file
+	"go":         GO_CODE_TEMPLATE,
+	"cpp":        CPP_CODE_TEMPLATE,
+	"java":       JAVA_CODE_TEMPLATE,
+}
+
+var SIGNATURE_CODE_TEMPLATE = map[string]string{
+	"python":     PYTHON_SIGNATURE_CODE_TEMPLATE,
+	"javascript": JAVASCRIPT_SIGNATURE_CODE_TEMPLATE,
+}
@@ -5,6 +5,23 @@ const (
 	RUN_QUESTION    = "run"
 	SUBMIT_QUESTION = "submit"
 
+	// number of sample test cases of questions without test case visibility
+	SAMPLE_TEST_CASES = 2
+
+	// limits of a test case for questions without their own, in milliseconds of cpu time and MiB
+	DEFAULT_TIME_LIMIT   = 2000
+	DEFAULT_MEMORY_LIMIT = 256
+
+	// Code executors
+	EXECUTOR_DOCKER      = "docker"
+	EXECUTOR_PROCESS     = "process"
+	SANDBOX_INIT_COMMAND = "sandbox-init"
+
+	// Limits of multi-file projects run by the compiler
+	MAX_PROJECT_FILES     = 50
+	MAX_PROJECT_FILE_SIZE = 256 * 1024
+	MAX_PROJECT_SIZE      = 1024 * 1024
+
 	// Database
 	USER_COLLECTION            = "users"
 	QUESTION_COLLECTION        = "questions"
@@ -12,14 +29,29 @@ const (
 	COMMENT_COLLECTION         = "comments"
 	CODE_SUBMISSION_COLLECTION = "submissions"
 	CHALLENGE_COLLECTION       = "challenges"
+	SIMILARITY_COLLECTION      = "similarities"
+	SESSION_COLLECTION         = "sessions"
+
+	// Types of the emails sent through the email queue
+	EMAIL_TYPE_VERIFICATION   = "verification"
+	EMAIL_TYPE_PASSWORD_RESET = "password_reset"
+
+	// Redis key prefix of the sessions whose access tokens are rejected
+	REVOKED_SESSION_CACHE_PREFIX = "revoked_session:"
 
 	// Auth API Endpoints
 	AUTH_API_BASE_ENDPOINT                     = "/api/v1/auth"
 	AUTH_API_LOGIN_ENDPOINT                    = "/login"
 	AUTH_API_REGISTER_ENDPOINT                 = "/register"
 	AUTH_API_LOGOUT_ENDPOINT                   = "/logout"
+	AUTH_API_LOGOUT_ALL_ENDPOINT               = "/logout-all"
+	AUTH_API_REFRESH_ENDPOINT                  = "/refresh"
+	AUTH_API_GET_SESSIONS_ENDPOINT             = "/sessions"
+	AUTH_API_REVOKE_SESSION_ENDPOINT           = "/sessions/:id"
+	AUTH_API_REVOKE_OTHER_SESSIONS_ENDPOINT    = "/sessions"
 	AUTH_API_EMAIL_VERIFY_ENDPOINT             = "/email/verify"
 	AUTH_API_FORGOT_PASSWORD_ENDPOINT          = "/forgot-password"
+	AUTH_API_RESET_PASSWORD_ENDPOINT           = "/reset-password"
 	AUTH_API_RESEND_VERIFICATION_CODE_ENDPOINT = "/:username/resend-verification-code"
 
 	// Question API Endpoints
@@ -32,6 +64,8 @@ const (
 	QUESTION_API_GET_BY_USER_ENDPOINT                     = "/user"
 	QUESTION_API_GET_QUESTIONS_SUBMITTED_BY_USER_ENDPOINT = "/submitted"
 	QUESTIONS_API_GET_SUBMISSIONS_ON_A_QUESTION_ENDPOINT  = "/:id/submissions"
+	QUESTION_API_GENERATE_TEST_CASES_ENDPOINT             = "/:id/test-cases/generate"
+	QUESTION_API_GET_SIMILAR_SUBMISSIONS_ENDPOINT         = "/:id/similarities"
 
 	// Blog API Endpoints
 	BLOG_API_BASE_ENDPOINT           = "/api/v1/blogs"
@@ -47,6 +81,11 @@ const (
 	CODE_EXECUTION_API_BASE_ENDPOINT     = "/api/v1/questions/:id/execute/"
 	COMPILER_CODE_EXECUTION_API_ENDPOINT = "/api/v1/code"
 
+	// Submission API Endpoints
+	SUBMISSION_API_BASE_ENDPOINT      = "/api/v1/submissions"
+	SUBMISSION_API_GET_BY_ID_ENDPOINT = "/:id"
+	SUBMISSION_API_EVENTS_ENDPOINT    = "/:id/events"
+
 	// Quiz API Endpoints
 	CHALLENGE_API_BASE_ENDPOINT                          = "/api/v1/challenges"
 	CHALLENGE_API_ALL_CHALLENGES_ENDPOINT                = "/all"
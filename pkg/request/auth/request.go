@@ -22,33 +22,57 @@ type VerifyEmailRequest struct {
 }
 
 type ForgotPasswordRequest struct {
-	Username        string `json:"username" validate:"required,min=6,max=20"`
-	Email           string `json:"email" validate:"required,email"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token" validate:"required"`
 	Password        string `json:"password" validate:"required,min=8,max=20"`
 	ConfirmPassword string `json:"confirmPassword" validate:"required,min=8,max=20"`
 }
 
 // Question requests
 type CreateQuestionRequest struct {
-	Title        string               `json:"title" validate:"required,min=5"`
-	Description  string               `json:"description" validate:"required"`
-	Difficulty   models.Difficulty    `json:"difficulty" validate:"required"`
-	Tags         []string             `json:"tags" validate:"required"`
-	Companies    []string             `json:"companies"`
-	Hints        []string             `json:"hints"`
-	TestCases    []models.TestCase    `json:"testCases" validate:"required"`
-	CodeSnippets []models.CodeSnippet `json:"codeSnippets" validate:"required"`
+	Title             string                    `json:"title" validate:"required,min=5"`
+	Description       string                    `json:"description" validate:"required"`
+	Difficulty        models.Difficulty         `json:"difficulty" validate:"required"`
+	Tags              []string                  `json:"tags" validate:"required"`
+	Companies         []string                  `json:"companies"`
+	Hints             []string                  `json:"hints"`
+	Mode              models.QuestionMode       `json:"mode" validate:"omitempty,oneof=function stdio interactive"`
+	TimeLimit         int64                     `json:"timeLimit" validate:"omitempty,min=100,max=10000"`
+	MemoryLimit       int64                     `json:"memoryLimit" validate:"omitempty,min=16,max=512"`
+	TestCases         []models.TestCase         `json:"testCases" validate:"required,dive"`
+	Signature         *models.Signature         `json:"signature" validate:"omitempty"`
+	Checker           *models.Checker           `json:"checker" validate:"omitempty"`
+	Interactor        *models.Interactor        `json:"interactor" validate:"omitempty"`
+	ReferenceSolution *models.ReferenceSolution `json:"referenceSolution" validate:"omitempty"`
+	Generator         *models.Generator         `json:"generator" validate:"omitempty"`
+	CodeSnippets      []models.CodeSnippet      `json:"codeSnippets" validate:"required"`
 }
 
 type UpdateQuestionRequest struct {
-	Title        string               `json:"title"`
-	Description  string               `json:"description"`
-	Difficulty   models.Difficulty    `json:"difficulty"`
-	Tags         []string             `json:"tags"`
-	Companies    []string             `json:"companies"`
-	Hints        []string             `json:"hints"`
-	TestCases    []models.TestCase    `json:"testCases"`
-	CodeSnippets []models.CodeSnippet `json:"codeSnippets"`
+	Title             string                    `json:"title"`
+	Description       string                    `json:"description"`
+	Difficulty        models.Difficulty         `json:"difficulty"`
+	Tags              []string                  `json:"tags"`
+	Companies         []string                  `json:"companies"`
+	Hints             []string                  `json:"hints"`
+	Mode              models.QuestionMode       `json:"mode" validate:"omitempty,oneof=function stdio interactive"`
+	TimeLimit         int64                     `json:"timeLimit" validate:"omitempty,min=100,max=10000"`
+	MemoryLimit       int64                     `json:"memoryLimit" validate:"omitempty,min=16,max=512"`
+	TestCases         []models.TestCase         `json:"testCases" validate:"omitempty,dive"`
+	Signature         *models.Signature         `json:"signature" validate:"omitempty"`
+	Checker           *models.Checker           `json:"checker" validate:"omitempty"`
+	Interactor        *models.Interactor        `json:"interactor" validate:"omitempty"`
+	ReferenceSolution *models.ReferenceSolution `json:"referenceSolution" validate:"omitempty"`
+	Generator         *models.Generator         `json:"generator" validate:"omitempty"`
+	CodeSnippets      []models.CodeSnippet      `json:"codeSnippets"`
+}
+
+type GenerateTestCasesRequest struct {
+	Count int   `json:"count" validate:"required,min=1,max=50"`
+	Seed  int64 `json:"seed"` // seed of the first test case, a random one is used when not set
 }
 
 // Blog requests
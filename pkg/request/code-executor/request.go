@@ -1,12 +1,21 @@
 package codeexecutor
 
 type ExecuteQuestion struct {
-	Language string `json:"language" validate:"required"`
+	Language string `json:"language" validate:"required,oneof=python javascript go cpp java"`
 	Code     string `json:"code" validate:"required"`
 	Type     string `json:"type" validate:"required"` // "run" or "submit"
 }
 
+type File struct {
+	Path    string `json:"path" validate:"required"`
+	Content string `json:"content"`
+}
+
 type ExecuteCompilerCode struct {
-	Code     string `json:"code" validate:"required"`
-	Language string `json:"language" validate:"required"`
+	Code     string   `json:"code" validate:"required_without=Files"`
+	Language string   `json:"language" validate:"required"`
+	Files    []File   `json:"files" validate:"omitempty,dive"` // a multi-file project run from Entry
+	Entry    string   `json:"entry" validate:"required_with=Files"`
+	Stdin    string   `json:"stdin" validate:"max=1048576"`
+	Args     []string `json:"args" validate:"max=32,dive,max=1024"`
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package codeexecutor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/utils"
+)
+
+func TestExecuteCompilerCodeValidation(t *testing.T) {
+	manyArgs := make([]string, 33)
+	for i := range manyArgs {
+		manyArgs[i] = "a"
+	}
+
+	tests := []struct {
+		name    string
+		body    ExecuteCompilerCode
+		wantErr bool
+	}{
+		{"code only", ExecuteCompilerCode{Code: "print(1)", Language: "python"}, false},
+		{"stdin and args", ExecuteCompilerCode{Code: "print(1)", Language: "python", Stdin: "1 2\n", Args: []string{"-v", "a b"}}, false},
+		{"empty arg", ExecuteCompilerCode{Code: "print(1)", Language: "python", Args: []string{""}}, false},
+		{"largest stdin", ExecuteCompilerCode{Code: "print(1)", Language: "python", Stdin: strings.Repeat("a", 1048576)}, false},
+		{"too large stdin", ExecuteCompilerCode{Code: "print(1)", Language: "python", Stdin: strings.Repeat("a", 1048577)}, true},
+		{"too many args", ExecuteCompilerCode{Code: "print(1)", Language: "python", Args: manyArgs}, true},
+		{"too long arg", ExecuteCompilerCode{Code: "print(1)", Language: "python", Args: []string{strings.Repeat("a", 1025)}}, true},
+		{"missing language", ExecuteCompilerCode{Code: "print(1)"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateRequest(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
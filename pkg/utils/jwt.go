@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
 	"time"
 
@@ -11,10 +15,11 @@ import (
 )
 
 type JWTPayload struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Username string `json:"username"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
 }
 
 func GenerateToken(payload JWTPayload) (string, error) {
@@ -23,7 +28,8 @@ func GenerateToken(payload JWTPayload) (string, error) {
 		"name":     payload.Name,
 		"email":    payload.Email,
 		"username": payload.Username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		"sid":      payload.SessionID,
+		"exp":      time.Now().Add(config.Config.ACCESS_TOKEN_TTL).Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(config.Config.JWT_SECRET_KEY))
@@ -34,6 +40,22 @@ func GenerateToken(payload JWTPayload) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateOpaqueToken returns a random token, e.g. a refresh token or a password reset token. Only its hash is stored.
+func GenerateOpaqueToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashToken hashes a random token for storage, unlike passwords they are long enough for a fast hash
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 func GetDecodedUserFromContext(c *gin.Context) (JWTPayload, error) {
 	userData, exists := c.Get(config.Config.JWT_DECODED_PAYLOAD)
 	if !exists {
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	tokens := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		token, err := GenerateOpaqueToken()
+		if err != nil {
+			t.Fatalf("GenerateOpaqueToken() error = %v", err)
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(decoded) != 32 {
+			t.Fatalf("GenerateOpaqueToken() = %q, want 32 random bytes", token)
+		}
+		if tokens[token] {
+			t.Fatalf("GenerateOpaqueToken() returned %q twice", token)
+		}
+		tokens[token] = true
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+
+	for _, tt := range tests {
+		if got := HashToken(tt.token); got != tt.want {
+			t.Errorf("HashToken(%q) = %s, want %s", tt.token, got, tt.want)
+		}
+	}
+}
@@ -1,21 +1,38 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
 	"github.com/Shashank-Vishwakarma/code-pulse-backend/pkg/constants"
 )
 
-func extractFunctionName(codeSnippet string) string {
-	patterns := []string{
-		`def\s+(\w+)\s*\(`,       // Python function: def func_name(a, b):
+var functionNamePatterns = map[string][]string{
+	"python": {
+		`def\s+(\w+)\s*\(`, // Python function: def func_name(a, b):
+	},
+	"javascript": {
 		`const\s+(\w+)\s*=\s*\(`, // JavaScript function: const func_name = (a, b) => {}
 		`function\s+(\w+)\s*\(`,  // JavaScript function: function func_name(a, b) {}
-	}
+	},
+	"go": {
+		`func\s+(\w+)\s*\(`, // Go function: func funcName(a int, b int) int {}
+	},
+	"cpp": {
+		`(?m)^[ \t]*[\w:<>,*&\[\] \t]+?[ \t*&]+(\w+)[ \t]*\(`, // C++ function: vector<int> funcName(vector<int>& a, int b) {}
+	},
+	"java": {
+		`(?m)^[ \t]*(?:(?:public|private|protected|static|final)[ \t]+)*[\w<>,.\[\] \t]+?[ \t]+(\w+)[ \t]*\(`, // Java method: public int[] funcName(int[] a, int b) {}
+	},
+}
 
-	for _, pattern := range patterns {
+func extractFunctionName(language, codeSnippet string) string {
+	for _, pattern := range functionNamePatterns[language] {
 		rg := regexp.MustCompile(pattern)
 		matches := rg.FindStringSubmatch(codeSnippet)
 
@@ -27,31 +44,263 @@ func extractFunctionName(codeSnippet string) string {
 	return ""
 }
 
-func GenerateCodeTemplate(testCases []models.TestCase, language, codeSnippet, userCode string) string {
+// GenerateCodeTemplate wraps the user code in the harness of a question without a signature.
+// The harness reads the test case to run from stdin, see GenerateHarnessInput.
+func GenerateCodeTemplate(language, codeSnippet, userCode string) string {
 	// get function name from the code snippet
-	functionName := extractFunctionName(codeSnippet)
-
-	// create string format for testcases
-	testcases := "["
-	for _, tc := range testCases {
-		testCase := ""
-		if language == "python" {
-			testCase = "{\"input\": \"" + tc.Input + "\", \"output\": \"" + tc.Output + "\"},"
-		}
-		if language == "javascript" {
-			testCase = "{input: " + fmt.Sprintf("%q", tc.Input) + ", output: " + fmt.Sprintf("%q", tc.Output) + "},"
-		}
+	functionName := extractFunctionName(language, codeSnippet)
 
-		testcases += testCase
+	switch language {
+	case "go", "cpp", "java":
+		return formatTypedCodeTemplate(language, functionName, userCode)
 	}
-	testcases += "]"
 
-	codeTemplate := fmt.Sprintf(constants.GOLANG_CODE_TEMPLATE[language], userCode, testcases, functionName)
+	codeTemplate := fmt.Sprintf(constants.GOLANG_CODE_TEMPLATE[language], userCode, functionName)
 
 	if language == "javascript" {
 		code := "const output = eval(`${func_name}${input}`)"
-		codeTemplate = fmt.Sprintf(constants.GOLANG_CODE_TEMPLATE[language], userCode, testcases, functionName, code)
+		codeTemplate = fmt.Sprintf(constants.GOLANG_CODE_TEMPLATE[language], userCode, functionName, code)
 	}
 
 	return codeTemplate
-}
\ No newline at end of file
+}
+
+// typedTestCase is a test case as read by the Go, C++ and Java harnesses and the signature harnesses
+type typedTestCase struct {
+	Input string            `json:"input"`
+	Args  []json.RawMessage `json:"args"`
+}
+
+func formatTypedCodeTemplate(language, functionName, userCode string) string {
+	switch language {
+	case "go":
+		// the harness imports have to come before the user code, so the package clause goes
+		userCode = regexp.MustCompile(`(?m)^\s*package\s+\w+\s*$`).ReplaceAllString(userCode, "")
+	case "java":
+		// Main has to be the only public class of Main.java
+		userCode = regexp.MustCompile(`(?m)^public\s+class\s+`).ReplaceAllString(userCode, "class ")
+	}
+
+	return fmt.Sprintf(constants.GOLANG_CODE_TEMPLATE[language], userCode, functionName)
+}
+
+// GenerateSignatureCodeTemplate generates the harness of a question with a signature.
+// The harness reads the test case to run from stdin, see GenerateHarnessInput.
+func GenerateSignatureCodeTemplate(language string, signature *models.Signature, userCode string) (string, error) {
+	// the statically typed harnesses decode by the declared types of the user's function
+	if _, ok := constants.SIGNATURE_CODE_TEMPLATE[language]; !ok {
+		if _, ok := constants.GOLANG_CODE_TEMPLATE[language]; !ok {
+			return "", fmt.Errorf("unsupported language: %s", language)
+		}
+		return formatTypedCodeTemplate(language, signature.FunctionName, userCode), nil
+	}
+
+	parameterTypes := []string{}
+	for _, parameter := range signature.Parameters {
+		parameterTypes = append(parameterTypes, parameter.Type)
+	}
+
+	harness := encodeJSON(struct {
+		FunctionName   string   `json:"functionName"`
+		ParameterTypes []string `json:"parameterTypes"`
+		ReturnType     string   `json:"returnType"`
+	}{
+		FunctionName:   signature.FunctionName,
+		ParameterTypes: parameterTypes,
+		ReturnType:     signature.ReturnType,
+	})
+
+	return fmt.Sprintf(constants.SIGNATURE_CODE_TEMPLATE[language], userCode, strconv.Quote(harness), signature.FunctionName), nil
+}
+
+// GenerateHarnessInput returns the test case as the harness of the language reads it from stdin.
+// Only the input goes to the program, its output is checked by the judge.
+// With a signature the arguments are taken from the input by parameter name and converted to
+// canonical JSON of their declared type, the Go, C++ and Java harnesses get canonical JSON of every value.
+func GenerateHarnessInput(testCase models.TestCase, language string, signature *models.Signature) (string, error) {
+	if signature != nil {
+		values, err := parseSignatureInput(testCase.Input, signature.Parameters)
+		if err != nil {
+			return "", err
+		}
+
+		args := []json.RawMessage{}
+		for i, value := range values {
+			args = append(args, json.RawMessage(toCanonicalJSONOfType(value, signature.Parameters[i].Type)))
+		}
+
+		return encodeJSON(typedTestCase{Input: testCase.Input, Args: args}), nil
+	}
+
+	switch language {
+	case "go", "cpp", "java":
+		args := []json.RawMessage{}
+		for _, arg := range splitTestCaseInput(testCase.Input) {
+			args = append(args, json.RawMessage(ToCanonicalJSON(arg)))
+		}
+
+		return encodeJSON(typedTestCase{Input: testCase.Input, Args: args}), nil
+	}
+
+	// the python and javascript harnesses evaluate the arguments as written by the author
+	return encodeJSON(map[string]string{"input": testCase.Input}), nil
+}
+
+// parseSignatureInput returns the argument of every parameter from an input that is either
+// a JSON object keyed by parameter name or written like "nums = [1, 2]; target = 3"
+func parseSignatureInput(input string, parameters []models.Parameter) ([]string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(input), &object); err == nil {
+		values := []string{}
+		for _, parameter := range parameters {
+			value, ok := object[parameter.Name]
+			if !ok {
+				return nil, fmt.Errorf("missing argument %s in test case input %s", parameter.Name, input)
+			}
+			values = append(values, string(value))
+		}
+		return values, nil
+	}
+
+	// find where the value of every parameter starts, so values may contain ";" and "="
+	starts := []int{}
+	ends := []int{}
+	offset := 0
+	for _, parameter := range parameters {
+		rg := regexp.MustCompile(`(?:^|;)\s*` + regexp.QuoteMeta(parameter.Name) + `\s*=`)
+		location := rg.FindStringIndex(input[offset:])
+		if location == nil {
+			return nil, fmt.Errorf("missing argument %s in test case input %s", parameter.Name, input)
+		}
+
+		if len(starts) > 0 {
+			ends = append(ends, offset+location[0])
+		}
+		starts = append(starts, offset+location[1])
+		offset += location[1]
+	}
+	ends = append(ends, len(input))
+
+	values := []string{}
+	for i := range starts {
+		values = append(values, strings.TrimSpace(input[starts[i]:ends[i]]))
+	}
+
+	return values, nil
+}
+
+// toCanonicalJSONOfType is ToCanonicalJSON, except that strings and chars do not need quotes
+func toCanonicalJSONOfType(text, valueType string) string {
+	canonical := ToCanonicalJSON(text)
+
+	if valueType == "string" || valueType == "char" {
+		var value interface{}
+		if err := json.Unmarshal([]byte(canonical), &value); err == nil {
+			if _, ok := value.(string); !ok {
+				return encodeJSON(strings.TrimSpace(text))
+			}
+		}
+	}
+
+	return canonical
+}
+
+// HarnessExpectedOutput returns the expected output of a test case in the form the harness of the language prints outputs
+func HarnessExpectedOutput(output, language string, signature *models.Signature) string {
+	if signature != nil {
+		return toCanonicalJSONOfType(output, signature.ReturnType)
+	}
+
+	switch language {
+	case "go", "cpp", "java":
+		return ToCanonicalJSON(output)
+	}
+
+	// the python and javascript harnesses print outputs the way the author writes them
+	return output
+}
+
+// splitTestCaseInput returns the values of an input like "nums = [1, 2]; target = 3"
+func splitTestCaseInput(input string) []string {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+
+	values := []string{}
+	for _, arg := range strings.Split(input, ";") {
+		parts := strings.SplitN(arg, "=", 2)
+		values = append(values, strings.TrimSpace(parts[len(parts)-1]))
+	}
+
+	return values
+}
+
+// ToCanonicalJSON turns a value written by a question author into compact JSON.
+// Python literals (True, None, 'a') are accepted and anything that is not JSON is taken as a string.
+func ToCanonicalJSON(text string) string {
+	text = strings.TrimSpace(text)
+
+	for _, candidate := range []string{text, normalizePythonLiteral(text)} {
+		if !json.Valid([]byte(candidate)) {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(candidate))
+		decoder.UseNumber()
+
+		var value interface{}
+		if err := decoder.Decode(&value); err == nil {
+			return encodeJSON(canonicalNumbers(value))
+		}
+	}
+
+	return encodeJSON(text)
+}
+
+func normalizePythonLiteral(text string) string {
+	text = strings.ReplaceAll(text, "'", `"`)
+	return regexp.MustCompile(`\b(True|False|None)\b`).ReplaceAllStringFunc(text, func(match string) string {
+		switch match {
+		case "True":
+			return "true"
+		case "False":
+			return "false"
+		}
+		return "null"
+	})
+}
+
+// canonicalNumbers keeps integers as they are and turns every other number into a float64,
+// so that 2.0 and 2.00 are both written as 2 like the harnesses do
+func canonicalNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return v
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return v
+		}
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = canonicalNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = canonicalNumbers(v[key])
+		}
+	}
+
+	return value
+}
+
+func encodeJSON(value interface{}) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	return strings.TrimRight(buffer.String(), "\n")
+}
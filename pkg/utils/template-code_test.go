@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Shashank-Vishwakarma/code-pulse-backend/internal/models"
+)
+
+func TestToCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"integer", "3", "3"},
+		{"float with trailing zeros", "2.50", "2.5"},
+		{"float without fraction", "2.0", "2"},
+		{"large integer kept exactly", "9007199254740993", "9007199254740993"},
+		{"array with spaces", "[1, 2,  3]", "[1,2,3]"},
+		{"nested array", "[[1, 2], [3.0]]", "[[1,2],[3]]"},
+		{"object", `{"b": 1, "a": [true]}`, `{"a":[true],"b":1}`},
+		{"python literals", "[True, False, None]", "[true,false,null]"},
+		{"python string", "'abc'", `"abc"`},
+		{"JSON string", `"a b"`, `"a b"`},
+		{"plain text is a string", "hello world", `"hello world"`},
+		{"surrounding whitespace", "  [1]\n", "[1]"},
+		{"html is not escaped", `"<a&b>"`, `"<a&b>"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToCanonicalJSON(tt.text); got != tt.want {
+				t.Errorf("ToCanonicalJSON(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTestCaseInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "  ", nil},
+		{"single argument", "n = 5", []string{"5"}},
+		{"several arguments", "nums = [2,7,11,15]; target = 9", []string{"[2,7,11,15]", "9"}},
+		{"value without a name", "[1,2]", []string{"[1,2]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitTestCaseInput(tt.input); !slices.Equal(got, tt.want) {
+				t.Errorf("splitTestCaseInput(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractFunctionName(t *testing.T) {
+	tests := []struct {
+		language string
+		snippet  string
+		want     string
+	}{
+		{"python", "def two_sum(nums, target):\n    pass", "two_sum"},
+		{"javascript", "const twoSum = (nums, target) => {}", "twoSum"},
+		{"javascript", "function twoSum(nums, target) {}", "twoSum"},
+		{"go", "func twoSum(nums []int, target int) []int {\n}", "twoSum"},
+		{"cpp", "vector<int> twoSum(vector<int>& nums, int target) {\n}", "twoSum"},
+		{"cpp", "class Solution {\npublic:\n    bool isValid(string s) {\n    }\n};", "isValid"},
+		{"java", "class Solution {\n    public int[] twoSum(int[] nums, int target) {\n    }\n}", "twoSum"},
+		{"java", "public static List<List<Integer>> threeSum(int[] nums) {}", "threeSum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language+" "+tt.want, func(t *testing.T) {
+			if got := extractFunctionName(tt.language, tt.snippet); got != tt.want {
+				t.Errorf("extractFunctionName(%s, %q) = %q, want %q", tt.language, tt.snippet, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSignatureInput(t *testing.T) {
+	parameters := []models.Parameter{
+		{Name: "nums", Type: "int[]"},
+		{Name: "target", Type: "int"},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"assignments", "nums = [2,7,11,15]; target = 9", []string{"[2,7,11,15]", "9"}, false},
+		{"JSON object", `{"target": 9, "nums": [2, 7]}`, []string{"[2, 7]", "9"}, false},
+		{"without spaces", "nums=[1];target=2", []string{"[1]", "2"}, false},
+		{"values with separators", `nums = [1]; target = "a;b=c"`, []string{"[1]", `"a;b=c"`}, false},
+		{"missing argument", "nums = [1]", nil, true},
+		{"missing argument in JSON", `{"nums": [1]}`, nil, true},
+		{"arguments out of order", "target = 9; nums = [1]", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSignatureInput(tt.input, parameters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSignatureInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseSignatureInput(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCanonicalJSONOfType(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		valueType string
+		want      string
+	}{
+		{"number", "2.0", "double", "2"},
+		{"array", "[1, 2]", "int[]", "[1,2]"},
+		{"quoted string", `"abc"`, "string", `"abc"`},
+		{"unquoted string", "abc", "string", `"abc"`},
+		{"string looking like a number", "123", "string", `"123"`},
+		{"string looking like a boolean", " true ", "string", `"true"`},
+		{"char", "a", "char", `"a"`},
+		{"python string", "'a'", "char", `"a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toCanonicalJSONOfType(tt.text, tt.valueType); got != tt.want {
+				t.Errorf("toCanonicalJSONOfType(%q, %s) = %s, want %s", tt.text, tt.valueType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateHarnessInput(t *testing.T) {
+	signature := &models.Signature{
+		FunctionName: "twoSum",
+		Parameters:   []models.Parameter{{Name: "nums", Type: "int[]"}, {Name: "name", Type: "string"}},
+		ReturnType:   "int[]",
+	}
+
+	tests := []struct {
+		name      string
+		input     string
+		language  string
+		signature *models.Signature
+		want      string
+		wantErr   bool
+	}{
+		{"python", `nums = [1, 2]; name = "a"`, "python", nil, `{"input":"nums = [1, 2]; name = \"a\""}`, false},
+		{"javascript", "x = 1", "javascript", nil, `{"input":"x = 1"}`, false},
+		{"typed", "nums = [1, 2]; flag = True", "go", nil, `{"input":"nums = [1, 2]; flag = True","args":[[1,2],true]}`, false},
+		{"signature", "nums = [1, 2]; name = a", "python", signature, `{"input":"nums = [1, 2]; name = a","args":[[1,2],"a"]}`, false},
+		{"signature in a typed language", `{"nums": [3], "name": "b"}`, "java", signature, `{"input":"{\"nums\": [3], \"name\": \"b\"}","args":[[3],"b"]}`, false},
+		{"signature argument missing", "nums = [1]", "cpp", signature, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateHarnessInput(models.TestCase{Input: tt.input, Output: "secret"}, tt.language, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateHarnessInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GenerateHarnessInput(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}